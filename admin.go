@@ -0,0 +1,190 @@
+package mockhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// adminPath is the path prefix of the admin API endpoints.
+const adminPath = "/__admin"
+
+// WithAdminAPI exposes the admin API (see APIMock.AdminHandler) under /__admin on the mocked API server, so that
+// out-of-process clients can manage its stubs and inspect its invocations. Admin requests are not recorded as
+// invocations.
+func WithAdminAPI() Option {
+	return func(mockedAPI *APIMock) {
+		mockedAPI.adminHandler = mockedAPI.AdminHandler()
+	}
+}
+
+// AdminHandler returns the handler of the admin API, which can be served on a separate listener. Its JSON endpoints are:
+//
+//	POST   /__admin/stubs            creates a stub described as in stub files (see LoadStubsFS) and returns its id
+//	GET    /__admin/stubs            lists the stubs
+//	DELETE /__admin/stubs            removes all the stubs
+//	DELETE /__admin/stubs/{id}       removes a stub
+//	GET    /__admin/invocations      lists the invocations, optionally filtered with the method and path query parameters
+//	DELETE /__admin/invocations      forgets all the invocations
+//	POST   /__admin/scenarios/reset  sets all the scenarios back to their started state
+func (mockedAPI *APIMock) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+adminPath+"/stubs", mockedAPI.adminCreateStub)
+	mux.HandleFunc("GET "+adminPath+"/stubs", mockedAPI.adminListStubs)
+	mux.HandleFunc("DELETE "+adminPath+"/stubs", mockedAPI.adminDeleteStubs)
+	mux.HandleFunc("DELETE "+adminPath+"/stubs/{id}", mockedAPI.adminDeleteStub)
+	mux.HandleFunc("GET "+adminPath+"/invocations", mockedAPI.adminListInvocations)
+	mux.HandleFunc("DELETE "+adminPath+"/invocations", func(writer http.ResponseWriter, request *http.Request) {
+		mockedAPI.ResetInvocations()
+		writer.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST "+adminPath+"/scenarios/reset", func(writer http.ResponseWriter, request *http.Request) {
+		mockedAPI.ResetScenarios()
+		writer.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+func isAdminPath(path string) bool {
+	return path == adminPath || strings.HasPrefix(path, adminPath+"/")
+}
+
+type adminStub struct {
+	ID          int    `json:"id"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Host        string `json:"host,omitempty"`
+	Status      int    `json:"status,omitempty"`
+	Conditional bool   `json:"conditional,omitempty"`
+}
+
+type adminInvocation struct {
+	ReceivedAt time.Time         `json:"receivedAt"`
+	Request    cassetteRequest   `json:"request"`
+	Response   *cassetteResponse `json:"response,omitempty"`
+}
+
+type adminError struct {
+	Error string `json:"error"`
+}
+
+func (mockedAPI *APIMock) adminCreateStub(writer http.ResponseWriter, request *http.Request) {
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		writeAdminJSON(writer, http.StatusBadRequest, adminError{Error: err.Error()})
+		return
+	}
+	definition := &stubDefinition{}
+	if err := yaml.Unmarshal(body, definition); err != nil {
+		writeAdminJSON(writer, http.StatusBadRequest, adminError{Error: fmt.Sprintf("invalid stub: %v", err)})
+		return
+	}
+	registered, err := definition.stub(mockedAPI, nil, "")
+	if err != nil {
+		writeAdminJSON(writer, http.StatusBadRequest, adminError{Error: fmt.Sprintf("invalid stub: %v", err)})
+		return
+	}
+	writeAdminJSON(writer, http.StatusCreated, adminStub{
+		ID:          registered.id,
+		Method:      strings.ToUpper(definition.Method),
+		Path:        definition.Path,
+		Host:        registered.call.Host,
+		Status:      registered.response.statusCode,
+		Conditional: !registered.isUnconditional(),
+	})
+}
+
+func (mockedAPI *APIMock) adminListStubs(writer http.ResponseWriter, request *http.Request) {
+	mockedAPI.mu.Lock()
+	stubs := []adminStub{}
+	for call, registeredStubs := range mockedAPI.calls {
+		for _, registered := range registeredStubs {
+			stub := adminStub{
+				ID:          registered.id,
+				Method:      strings.ToUpper(call.Method),
+				Path:        call.Path,
+				Host:        call.Host,
+				Conditional: !registered.isUnconditional(),
+			}
+			if registered.response != nil {
+				stub.Status = registered.response.statusCode
+			}
+			stubs = append(stubs, stub)
+		}
+	}
+	mockedAPI.mu.Unlock()
+
+	sort.Slice(stubs, func(i, j int) bool {
+		return stubs[i].ID < stubs[j].ID
+	})
+	writeAdminJSON(writer, http.StatusOK, stubs)
+}
+
+func (mockedAPI *APIMock) adminDeleteStubs(writer http.ResponseWriter, request *http.Request) {
+	mockedAPI.mu.Lock()
+	for call := range mockedAPI.calls {
+		// the call is kept so that its path template is not registered twice
+		mockedAPI.calls[call] = nil
+	}
+	mockedAPI.mu.Unlock()
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (mockedAPI *APIMock) adminDeleteStub(writer http.ResponseWriter, request *http.Request) {
+	id, err := strconv.Atoi(request.PathValue("id"))
+	if err != nil {
+		writeAdminJSON(writer, http.StatusBadRequest, adminError{Error: "invalid stub id"})
+		return
+	}
+
+	mockedAPI.mu.Lock()
+	defer mockedAPI.mu.Unlock()
+	for call, registeredStubs := range mockedAPI.calls {
+		for i, registered := range registeredStubs {
+			if registered.id == id {
+				mockedAPI.calls[call] = append(registeredStubs[:i:i], registeredStubs[i+1:]...)
+				writer.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+	}
+	writeAdminJSON(writer, http.StatusNotFound, adminError{Error: fmt.Sprintf("no stub with id %d", id)})
+}
+
+func (mockedAPI *APIMock) adminListInvocations(writer http.ResponseWriter, request *http.Request) {
+	method := request.URL.Query().Get("method")
+	path := request.URL.Query().Get("path")
+
+	invocations := []adminInvocation{}
+	for _, invocation := range mockedAPI.receivedInvocations() {
+		if method != "" && !strings.EqualFold(method, invocation.GetRequest().Method) {
+			continue
+		}
+		if path != "" && path != invocation.GetRequest().URL.Path {
+			continue
+		}
+		interaction := newCassetteInteraction(invocation, mockedAPI.redaction)
+		entry := adminInvocation{
+			ReceivedAt: invocation.receivedAt,
+			Request:    interaction.Request,
+		}
+		if invocation.GetResponse() != nil {
+			entry.Response = &interaction.Response
+		}
+		invocations = append(invocations, entry)
+	}
+	writeAdminJSON(writer, http.StatusOK, invocations)
+}
+
+func writeAdminJSON(writer http.ResponseWriter, statusCode int, content any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	_ = json.NewEncoder(writer).Encode(content)
+}
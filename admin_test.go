@@ -0,0 +1,216 @@
+package mockhttp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AdminAPI(t *testing.T) {
+	t.Parallel()
+
+	send := func(t *testing.T, method string, url string, body string) (int, string) {
+		request, err := http.NewRequest(method, url, strings.NewReader(body))
+		require.NoError(t, err)
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		responseBody, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response.StatusCode, string(responseBody)
+	}
+
+	t.Run("should create and list stubs", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithAdminAPI())
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/health").WithStatusCode(http.StatusNoContent)
+		apiURL := mockedAPI.GetURL().String()
+
+		// Act
+		status, body := send(t, http.MethodPost, apiURL+"/__admin/stubs",
+			`{"method": "GET", "path": "/users/{id}", "response": {"status": 200, "json": {"name": "John"}}}`)
+
+		// Assert
+		assert := assertions.New(t)
+		assert.Equal(http.StatusCreated, status)
+		assert.JSONEq(`{"id": 2, "method": "GET", "path": "/users/{id}", "status": 200}`, body)
+
+		status, body = send(t, http.MethodGet, apiURL+"/users/42", "")
+		assert.Equal(http.StatusOK, status)
+		assert.JSONEq(`{"name": "John"}`, body)
+
+		status, body = send(t, http.MethodGet, apiURL+"/__admin/stubs", "")
+		assert.Equal(http.StatusOK, status)
+		assert.JSONEq(`[
+			{"id": 1, "method": "GET", "path": "/health", "status": 204},
+			{"id": 2, "method": "GET", "path": "/users/{id}", "status": 200}
+		]`, body)
+
+		mockedAPI.Verify(http.MethodGet, "/users/42").HasBeenCalledOnce()
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should create and list virtual host stubs", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithAdminAPI())
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Host("admin.example.com").Stub(http.MethodGet, "/health").WithStatusCode(http.StatusNoContent)
+		apiURL := mockedAPI.GetURL().String()
+
+		// Act
+		status, body := send(t, http.MethodPost, apiURL+"/__admin/stubs",
+			`{"method": "GET", "path": "/users", "host": "api.example.com", "response": {"status": 200}}`)
+
+		// Assert
+		assert := assertions.New(t)
+		assert.Equal(http.StatusCreated, status)
+		assert.JSONEq(`{"id": 2, "method": "GET", "path": "/users", "host": "api.example.com", "status": 200}`, body)
+		status, body = send(t, http.MethodGet, apiURL+"/__admin/stubs", "")
+		assert.Equal(http.StatusOK, status)
+		assert.JSONEq(`[
+			{"id": 1, "method": "GET", "path": "/health", "host": "admin.example.com", "status": 204},
+			{"id": 2, "method": "GET", "path": "/users", "host": "api.example.com", "status": 200}
+		]`, body)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should keep serving when a stub body cannot be written", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithAdminAPI())
+		t.Cleanup(mockedAPI.Close)
+		apiURL := mockedAPI.GetURL().String()
+		status, _ := send(t, http.MethodPost, apiURL+"/__admin/stubs",
+			`{"method": "GET", "path": "/health", "response": {"status": 204, "body": "not allowed"}}`)
+		require.Equal(t, http.StatusCreated, status)
+
+		// Act
+		status, body := send(t, http.MethodGet, apiURL+"/health", "")
+
+		// Assert
+		assert := assertions.New(t)
+		assert.Equal(http.StatusNoContent, status)
+		assert.Empty(body)
+		status, _ = send(t, http.MethodGet, apiURL+"/__admin/stubs", "")
+		assert.Equal(http.StatusOK, status)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should reject invalid stubs", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithAdminAPI())
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		status, body := send(t, http.MethodPost, mockedAPI.GetURL().String()+"/__admin/stubs", `{"path": "/users"}`)
+
+		// Assert
+		assert := assertions.New(t)
+		assert.Equal(http.StatusBadRequest, status)
+		assert.JSONEq(`{"error": "invalid stub: method and path are required"}`, body)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should delete stubs", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.
+			Stub(http.MethodGet, "/users").WithStatusCode(http.StatusOK).
+			Stub(http.MethodGet, "/orders").WithStatusCode(http.StatusOK)
+		admin := httptest.NewServer(mockedAPI.AdminHandler())
+		t.Cleanup(admin.Close)
+
+		// Act
+		deleteStatus, _ := send(t, http.MethodDelete, admin.URL+"/__admin/stubs/1", "")
+		missingStatus, _ := send(t, http.MethodDelete, admin.URL+"/__admin/stubs/1", "")
+
+		// Assert
+		assert := assertions.New(t)
+		assert.Equal(http.StatusNoContent, deleteStatus)
+		assert.Equal(http.StatusNotFound, missingStatus)
+		_, body := send(t, http.MethodGet, admin.URL+"/__admin/stubs", "")
+		assert.JSONEq(`[{"id": 2, "method": "GET", "path": "/orders", "status": 200}]`, body)
+
+		status, _ := send(t, http.MethodDelete, admin.URL+"/__admin/stubs", "")
+		assert.Equal(http.StatusNoContent, status)
+		_, body = send(t, http.MethodGet, admin.URL+"/__admin/stubs", "")
+		assert.JSONEq(`[]`, body)
+	})
+
+	t.Run("should list and reset invocations", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithAdminAPI(), WithRedaction(Redaction{Headers: []string{"Authorization"}}))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.
+			Stub(http.MethodPost, "/users").WithJSON(http.StatusCreated, map[string]any{"id": 1}).
+			Stub(http.MethodGet, "/users").WithStatusCode(http.StatusOK)
+		apiURL := mockedAPI.GetURL().String()
+		request, err := http.NewRequest(http.MethodPost, apiURL+"/users?notify=true", strings.NewReader(`{"name": "John"}`))
+		require.NoError(t, err)
+		request.Header.Set("Authorization", "Bearer secret")
+		_, err = http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		send(t, http.MethodGet, apiURL+"/users", "")
+
+		// Act
+		status, body := send(t, http.MethodGet, apiURL+"/__admin/invocations?method=post&path=/users", "")
+
+		// Assert
+		assert := assertions.New(t)
+		assert.Equal(http.StatusOK, status)
+		invocations := []adminInvocation{}
+		require.NoError(t, json.Unmarshal([]byte(body), &invocations))
+		require.Len(t, invocations, 1)
+		assert.Equal(http.MethodPost, invocations[0].Request.Method)
+		assert.Equal("notify=true", invocations[0].Request.Query)
+		assert.Equal(`{"name": "John"}`, invocations[0].Request.Body)
+		assert.Equal("Bearer [REDACTED]", invocations[0].Request.Headers.Get("Authorization"))
+		require.NotNil(t, invocations[0].Response)
+		assert.Equal(http.StatusCreated, invocations[0].Response.Status)
+		assert.False(invocations[0].ReceivedAt.IsZero())
+
+		status, _ = send(t, http.MethodDelete, apiURL+"/__admin/invocations", "")
+		assert.Equal(http.StatusNoContent, status)
+		_, body = send(t, http.MethodGet, apiURL+"/__admin/invocations", "")
+		assert.JSONEq(`[]`, body)
+		mockedAPI.Verify(http.MethodPost, "/users").HasNotBeenCalled()
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should reset scenarios", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithAdminAPI())
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.SetScenarioState("order", "shipped")
+
+		// Act
+		status, _ := send(t, http.MethodPost, mockedAPI.GetURL().String()+"/__admin/scenarios/reset", "")
+
+		// Assert
+		assert := assertions.New(t)
+		assert.Equal(http.StatusNoContent, status)
+		assert.Equal(ScenarioStartedState, mockedAPI.GetScenarioState("order"))
+	})
+}
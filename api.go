@@ -1,9 +1,16 @@
 package mockhttp
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"maps"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"regexp"
 	"strings"
 	"sync"
 
@@ -15,60 +22,176 @@ type T testingmock.IT
 // APIMock is a representation of a mocked API. It allows to stub HTTP calls and verify invocations.
 type APIMock struct {
 	testServer  *httptest.Server
-	calls       map[HTTPCall]http.HandlerFunc
+	calls       map[HTTPCall][]*registeredStub
+	templates   []*pathTemplate
 	testState   T
+	name        string
 	invocations map[HTTPCall][]*Invocation
-	mu          sync.Mutex
+	journal     []*Invocation
+	// journalStart is the index in the journal of the first invocation received since the last ResetInvocations.
+	journalStart    int
+	fallback        http.HandlerFunc
+	contract        *openAPIDocument
+	specs           []*openAPIDocument
+	redaction       *Redaction
+	scenarios       map[string]string
+	address         string
+	logging         bool
+	withoutListener bool
+	tlsConfig       *tls.Config
+	http2           bool
+	replay          replaySettings
+	stubSequence    int
+	adminHandler    http.Handler
+	mu              sync.Mutex
 }
 
 // HTTPCall is a simple representation of an endpoint call.
 type HTTPCall struct {
 	Method string
 	Path   string
+	// Host is the virtual host of the call (see APIMock.Host), empty for the calls made to any host.
+	Host string
 }
 
+// Option configures an APIMock when it is created.
+type Option func(mockedAPI *APIMock)
+
 // API creates a new APIMock instance and starts a server exposing it. The server is automatically stopped during test cleanup.
-func API(testState T) *APIMock {
+func API(testState T, options ...Option) *APIMock {
 	mockedAPI := &APIMock{
-		calls:       map[HTTPCall]http.HandlerFunc{},
+		calls:       map[HTTPCall][]*registeredStub{},
+		scenarios:   map[string]string{},
 		testState:   testState,
 		invocations: map[HTTPCall][]*Invocation{},
 	}
+	for _, option := range options {
+		option(mockedAPI)
+	}
 
-	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, request *http.Request) {
-		call := HTTPCall{
-			Method: strings.ToLower(request.Method),
-			Path:   request.URL.Path,
+	if mockedAPI.withoutListener && mockedAPI.tlsConfig != nil {
+		testState.Fatal("TLS cannot be used without listener")
+	}
+	if mockedAPI.withoutListener && mockedAPI.http2 {
+		testState.Fatal("HTTP/2 cannot be used without listener")
+	}
+	if !mockedAPI.withoutListener {
+		var handler http.Handler = http.HandlerFunc(mockedAPI.serveHTTP)
+		if mockedAPI.http2 && mockedAPI.tlsConfig == nil {
+			handler = h2cHandler(handler)
 		}
-
-		mockedAPI.mu.Lock()
-		invocations := mockedAPI.invocations[call]
-		invocations = append(invocations, newInvocation(request, testState))
-		mockedAPI.invocations[call] = invocations
-		mockedAPI.mu.Unlock()
-
-		handler := mockedAPI.calls[call]
-		if handler != nil {
-			handler(res, request)
+		mockedAPI.testServer = httptest.NewUnstartedServer(handler)
+		mockedAPI.testServer.EnableHTTP2 = mockedAPI.http2
+		if mockedAPI.address != "" {
+			listener, err := net.Listen("tcp", mockedAPI.address)
+			if err != nil {
+				testState.Fatal(err)
+			} else {
+				_ = mockedAPI.testServer.Listener.Close()
+				mockedAPI.testServer.Listener = listener
+			}
+		}
+		if mockedAPI.tlsConfig != nil {
+			mockedAPI.testServer.TLS = mockedAPI.tlsConfig
+			mockedAPI.testServer.StartTLS()
 		} else {
-			res.WriteHeader(http.StatusNotFound)
-			testState.Fatalf("unmocked invocation %s %s\n", call.Method, call.Path)
+			mockedAPI.testServer.Start()
 		}
-	}))
-	mockedAPI.testServer = testServer
+	}
 	testState.Cleanup(mockedAPI.Close)
 
 	return mockedAPI
 }
 
+// WithAddress makes the server listen on the given TCP address (e.g. "localhost:8080") instead of a random local port.
+func WithAddress(address string) Option {
+	return func(mockedAPI *APIMock) {
+		mockedAPI.address = address
+	}
+}
+
+// WithName names the mocked API, e.g. after the partner API it stands for, so that its stubs are reported apart from
+// the ones of the other mocked APIs in the coverage report (see EnableCoverage).
+func WithName(name string) Option {
+	return func(mockedAPI *APIMock) {
+		mockedAPI.name = name
+	}
+}
+
+// WithInvocationLogging logs each invocation, along with the status code of its response, to the test state.
+func WithInvocationLogging() Option {
+	return func(mockedAPI *APIMock) {
+		mockedAPI.logging = true
+	}
+}
+
+func (mockedAPI *APIMock) serveHTTP(res http.ResponseWriter, request *http.Request) {
+	if mockedAPI.adminHandler != nil && isAdminPath(request.URL.Path) {
+		mockedAPI.adminHandler.ServeHTTP(res, request)
+		return
+	}
+
+	call := HTTPCall{
+		Method: strings.ToLower(request.Method),
+		Path:   request.URL.Path,
+	}
+
+	invocation := newInvocation(request, mockedAPI.testState)
+	invocation.redaction = mockedAPI.redaction
+	mockedAPI.mu.Lock()
+	invocations := mockedAPI.invocations[call]
+	invocations = append(invocations, invocation)
+	mockedAPI.invocations[call] = invocations
+	mockedAPI.journal = append(mockedAPI.journal, invocation)
+	mockedAPI.mu.Unlock()
+
+	if mockedAPI.contract != nil {
+		mockedAPI.contract.verifyRequest(mockedAPI.testState, invocation)
+	}
+
+	for _, spec := range mockedAPI.specs {
+		activeCoverage.Load().recordOperationInvocation(spec, request)
+	}
+
+	var handler http.HandlerFunc
+	if stub := mockedAPI.findStub(call, request, invocation.GetPayload()); stub != nil {
+		activeCoverage.Load().recordStubInvocation(mockedAPI.name, stub.call)
+		handler = stub.handler
+	} else {
+		handler = mockedAPI.fallback
+	}
+	if handler == nil {
+		res.WriteHeader(http.StatusNotFound)
+		mockedAPI.testState.Fatalf("unmocked invocation %s %s\n", call.Method, call.Path)
+		return
+	}
+
+	recorder := newResponseRecorder(res)
+	handler(recorder, request)
+	invocation.setResponse(recorder)
+	if mockedAPI.logging {
+		requestURI := mockedAPI.redaction.requestURI(request.URL)
+		mockedAPI.testState.Log(fmt.Sprintf("%s %s %d", request.Method, requestURI, recorder.getStatusCode()))
+	}
+	if mockedAPI.contract != nil {
+		mockedAPI.contract.verifyResponse(mockedAPI.testState, invocation, recorder)
+	}
+}
+
 // Close stops the underlying server. This method is automatically called during test cleanup.
 func (mockedAPI *APIMock) Close() {
-	mockedAPI.testServer.Close()
+	if mockedAPI.testServer != nil {
+		mockedAPI.testServer.Close()
+	}
 }
 
 // GetURL returns the URL of the API underlying server.
 func (mockedAPI *APIMock) GetURL() *url.URL {
-	testServerURL, err := url.Parse(mockedAPI.testServer.URL)
+	serverURL := inMemoryURL
+	if mockedAPI.testServer != nil {
+		serverURL = mockedAPI.testServer.URL
+	}
+	testServerURL, err := url.Parse(serverURL)
 	if err != nil {
 		mockedAPI.testState.Fatal(err)
 	}
@@ -101,3 +224,287 @@ func (mockedAPI *APIMock) Verify(method string, path string) *CallVerifier {
 		},
 	}
 }
+
+// ResetInvocations forgets all the invocations received so far, as far as verifications and the admin API are
+// concerned. They are still written to the cassette of Record, to the Pact contract of WithPactContract and to the
+// HAR exported by ExportHAR.
+func (mockedAPI *APIMock) ResetInvocations() *APIMock {
+	mockedAPI.mu.Lock()
+	defer mockedAPI.mu.Unlock()
+	mockedAPI.invocations = map[HTTPCall][]*Invocation{}
+	mockedAPI.journalStart = len(mockedAPI.journal)
+	return mockedAPI
+}
+
+// receivedInvocations returns the invocations received since the last ResetInvocations, in the order they were
+// received.
+func (mockedAPI *APIMock) receivedInvocations() []*Invocation {
+	mockedAPI.mu.Lock()
+	defer mockedAPI.mu.Unlock()
+	return append([]*Invocation{}, mockedAPI.journal[mockedAPI.journalStart:]...)
+}
+
+// anyMethod is the method of the stubs handling requests whatever their method.
+const anyMethod = "any"
+
+// findStub returns the most recently registered stub accepting the request, nil if there is none.
+// Stubs registered for the virtual host of the request are looked up first, then the ones registered for any host.
+// The candidate stubs and the scenario states are taken under the lock, the request matchers being then evaluated
+// without holding it so that they can use the mocked API (e.g. GetScenarioState).
+func (mockedAPI *APIMock) findStub(call HTTPCall, request *http.Request, payload []byte) *registeredStub {
+	mockedAPI.mu.Lock()
+	scenarios := maps.Clone(mockedAPI.scenarios)
+	hostsCandidates := []*stubCandidates{}
+	for _, host := range virtualHostCandidates(request.Host) {
+		hostCall := HTTPCall{Method: call.Method, Path: call.Path, Host: host}
+		hostsCandidates = append(hostsCandidates, mockedAPI.stubCandidates(hostCall))
+	}
+	mockedAPI.mu.Unlock()
+
+	for _, candidates := range hostsCandidates {
+		if stub, pathValues := candidates.find(scenarios, request, payload); stub != nil {
+			setPathValues(request, pathValues)
+			mockedAPI.selectStub(stub)
+			return stub
+		}
+	}
+	return nil
+}
+
+// stubCandidates are the stubs of a host which may handle a request.
+type stubCandidates struct {
+	// exact are the stubs registered for the exact call path, with the request method and with any method.
+	exact [][]*registeredStub
+	// templates are the stubs registered for the path templates matching the call path.
+	templates []templateCandidates
+}
+
+type templateCandidates struct {
+	pathValues map[string]string
+	stubs      []*registeredStub
+}
+
+// stubCandidates returns the stubs of the call host which may handle the call. It must be called while holding the
+// mocked API lock.
+func (mockedAPI *APIMock) stubCandidates(call HTTPCall) *stubCandidates {
+	candidates := &stubCandidates{}
+	for _, method := range []string{call.Method, anyMethod} {
+		stubs := mockedAPI.calls[HTTPCall{Method: method, Path: call.Path, Host: call.Host}]
+		candidates.exact = append(candidates.exact, stubs)
+	}
+	for _, template := range mockedAPI.templates {
+		if template.call.Host != call.Host {
+			continue
+		}
+		if template.call.Method != call.Method && template.call.Method != anyMethod {
+			continue
+		}
+		if pathValues, ok := template.match(call.Path); ok {
+			candidates.templates = append(candidates.templates, templateCandidates{
+				pathValues: pathValues,
+				stubs:      mockedAPI.calls[template.call],
+			})
+		}
+	}
+	return candidates
+}
+
+// find returns the most recently registered stub accepting the request along with its path values, nil if there is
+// none. Stubs registered for the exact call path are looked up first, then the ones registered for matching path
+// templates, the stubs of the request method and the ones of any method being considered together.
+func (candidates *stubCandidates) find(scenarios map[string]string, request *http.Request, payload []byte) (*registeredStub, map[string]string) {
+	var selected *registeredStub
+	for _, stubs := range candidates.exact {
+		selected = latestStub(selected, acceptingStub(stubs, scenarios, request, payload))
+	}
+	if selected != nil {
+		return selected, nil
+	}
+
+	var selectedPathValues map[string]string
+	for _, template := range candidates.templates {
+		stub := acceptingStub(template.stubs, scenarios, withPathValues(request, template.pathValues), payload)
+		if latest := latestStub(selected, stub); latest != selected {
+			selected = latest
+			selectedPathValues = template.pathValues
+		}
+	}
+	return selected, selectedPathValues
+}
+
+// acceptingStub returns the most recently registered of the stubs accepting the request, nil if there is none.
+func acceptingStub(stubs []*registeredStub, scenarios map[string]string, request *http.Request, payload []byte) *registeredStub {
+	for i := len(stubs) - 1; i >= 0; i-- {
+		if stubs[i].accepts(scenarios, request, payload) {
+			return stubs[i]
+		}
+	}
+	return nil
+}
+
+// selectStub makes the scenario of the stub handling the request transition to its new state, if any.
+func (mockedAPI *APIMock) selectStub(stub *registeredStub) {
+	if scenario := stub.scenario; scenario != nil && scenario.newState != "" {
+		mockedAPI.mu.Lock()
+		defer mockedAPI.mu.Unlock()
+		mockedAPI.scenarios[scenario.name] = scenario.newState
+	}
+}
+
+// latestStub returns the most recently registered of the given stubs, either of them being possibly nil.
+func latestStub(stub *registeredStub, other *registeredStub) *registeredStub {
+	if stub == nil || (other != nil && other.id > stub.id) {
+		return other
+	}
+	return stub
+}
+
+// GetScenarioState returns the current state of the named scenario.
+func (mockedAPI *APIMock) GetScenarioState(name string) string {
+	mockedAPI.mu.Lock()
+	defer mockedAPI.mu.Unlock()
+	return scenarioState(mockedAPI.scenarios, name)
+}
+
+// SetScenarioState sets the current state of the named scenario.
+func (mockedAPI *APIMock) SetScenarioState(name string, state string) *APIMock {
+	mockedAPI.mu.Lock()
+	defer mockedAPI.mu.Unlock()
+	mockedAPI.scenarios[name] = state
+	return mockedAPI
+}
+
+// ResetScenarios sets all the scenarios back to the ScenarioStartedState state.
+func (mockedAPI *APIMock) ResetScenarios() *APIMock {
+	mockedAPI.mu.Lock()
+	defer mockedAPI.mu.Unlock()
+	mockedAPI.scenarios = map[string]string{}
+	return mockedAPI
+}
+
+func scenarioState(scenarios map[string]string, name string) string {
+	if state, ok := scenarios[name]; ok {
+		return state
+	}
+	return ScenarioStartedState
+}
+
+var pathParameterRegexp = regexp.MustCompile(`\{([^/{}]+)\}`)
+
+// pathTemplate matches request paths against a stubbed path containing parameters such as /pets/{id}.
+type pathTemplate struct {
+	call       HTTPCall
+	pattern    *regexp.Regexp
+	parameters []string
+	// regexp is true when the stubbed path is a regular expression (e.g. a WireMock urlPathPattern).
+	regexp bool
+}
+
+func isPathTemplate(path string) bool {
+	return pathParameterRegexp.MatchString(path)
+}
+
+func newPathTemplate(call HTTPCall) *pathTemplate {
+	template := &pathTemplate{call: call}
+	pattern := strings.Builder{}
+	pattern.WriteString("^")
+	last := 0
+	for _, match := range pathParameterRegexp.FindAllStringSubmatchIndex(call.Path, -1) {
+		pattern.WriteString(regexp.QuoteMeta(call.Path[last:match[0]]))
+		pattern.WriteString("([^/]+)")
+		template.parameters = append(template.parameters, call.Path[match[2]:match[3]])
+		last = match[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(call.Path[last:]))
+	pattern.WriteString("$")
+	template.pattern = regexp.MustCompile(pattern.String())
+	return template
+}
+
+// match returns the path values of the path, by parameter name, and whether the path matches the template.
+func (template *pathTemplate) match(path string) (map[string]string, bool) {
+	values := template.pattern.FindStringSubmatch(path)
+	if values == nil {
+		return nil, false
+	}
+	pathValues := make(map[string]string, len(template.parameters))
+	for i, name := range template.parameters {
+		pathValues[name] = values[i+1]
+	}
+	return pathValues, true
+}
+
+// withPathValues returns the request with the given path values set, the request being cloned when there are some
+// so that the values are not seen by the stubs of other templates.
+func withPathValues(request *http.Request, pathValues map[string]string) *http.Request {
+	if len(pathValues) == 0 {
+		return request
+	}
+	request = request.Clone(request.Context())
+	setPathValues(request, pathValues)
+	return request
+}
+
+func setPathValues(request *http.Request, pathValues map[string]string) {
+	for name, value := range pathValues {
+		request.SetPathValue(name, value)
+	}
+}
+
+// responseRecorder is a http.ResponseWriter forwarding the response to the underlying writer while capturing it.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newResponseRecorder(writer http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: writer}
+}
+
+// WriteHeader captures the status code and forwards it to the underlying writer.
+func (recorder *responseRecorder) WriteHeader(statusCode int) {
+	if recorder.statusCode == 0 {
+		recorder.statusCode = statusCode
+	}
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write captures the data and forwards it to the underlying writer.
+func (recorder *responseRecorder) Write(data []byte) (int, error) {
+	if recorder.statusCode == 0 {
+		recorder.statusCode = http.StatusOK
+	}
+	recorder.body.Write(data)
+	return recorder.ResponseWriter.Write(data)
+}
+
+// Unwrap returns the underlying writer so that http.ResponseController can reach its optional interfaces.
+func (recorder *responseRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
+
+// Flush sends the buffered data to the client when the underlying writer supports it.
+func (recorder *responseRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the handler take over the connection when the underlying writer supports it, e.g. to upgrade it to a
+// WebSocket, the status code being then recorded as http.StatusSwitchingProtocols.
+func (recorder *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, readWriter, err := http.NewResponseController(recorder.ResponseWriter).Hijack()
+	if err == nil && recorder.statusCode == 0 {
+		recorder.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, readWriter, err
+}
+
+// getStatusCode returns the status code written by the handler, http.StatusOK if it did not write any.
+func (recorder *responseRecorder) getStatusCode() int {
+	if recorder.statusCode == 0 {
+		return http.StatusOK
+	}
+	return recorder.statusCode
+}
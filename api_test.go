@@ -1,8 +1,12 @@
 package mockhttp
 
 import (
+	"fmt"
+	"net"
 	"net/http"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/le-yams/gotestingmock"
 	"github.com/stretchr/testify/assert"
@@ -44,4 +48,91 @@ func Test_api_mock_should(t *testing.T) {
 		_, err = http.Get(endpointURL) // Should fail because server is closed
 		assert.Error(t, err)
 	})
+
+	t.Run("listen on the given address", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		address := listener.Addr().String()
+		require.NoError(t, listener.Close())
+
+		// Act
+		mockedAPI := API(testingmock.New(t), WithAddress(address))
+		t.Cleanup(mockedAPI.Close)
+
+		// Assert
+		assert.Equal(t, address, mockedAPI.GetHost())
+	})
+
+	t.Run("fail when unable to listen on the given address", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = listener.Close() })
+		testState := testingmock.New(t)
+
+		// Act
+		mockedAPI := API(testState, WithAddress(listener.Addr().String()))
+		t.Cleanup(mockedAPI.Close)
+
+		// Assert
+		testState.AssertFailedWithFatal()
+	})
+
+	t.Run("log invocations", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := &loggingT{MockedT: testingmock.New(t)}
+		mockedAPI := API(testState, WithInvocationLogging())
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/endpoint").WithStatusCode(http.StatusAccepted)
+
+		// Act
+		_, err := http.Get(mockedAPI.GetURL().JoinPath("endpoint").String() + "?foo=bar")
+		require.NoError(t, err)
+
+		// Assert
+		assert.Eventually(t, func() bool {
+			return assert.ObjectsAreEqual([]string{"GET /endpoint?foo=bar 202"}, testState.getLogs())
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("log invocations with their query redacted", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := &loggingT{MockedT: testingmock.New(t)}
+		mockedAPI := API(testState, WithInvocationLogging(), WithRedaction(Redaction{QueryParams: []string{"api_key"}}))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/endpoint").WithStatusCode(http.StatusAccepted)
+
+		// Act
+		_, err := http.Get(mockedAPI.GetURL().JoinPath("endpoint").String() + "?api_key=secret")
+		require.NoError(t, err)
+
+		// Assert
+		assert.Eventually(t, func() bool {
+			return assert.ObjectsAreEqual([]string{"GET /endpoint?api_key=%5BREDACTED%5D 202"}, testState.getLogs())
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+// loggingT is a mocked test state keeping track of the logged messages.
+type loggingT struct {
+	*testingmock.MockedT
+	logs []string
+	mu   sync.Mutex
+}
+
+func (testState *loggingT) Log(args ...any) {
+	testState.mu.Lock()
+	defer testState.mu.Unlock()
+	testState.logs = append(testState.logs, fmt.Sprint(args...))
+}
+
+func (testState *loggingT) getLogs() []string {
+	testState.mu.Lock()
+	defer testState.mu.Unlock()
+	return append([]string{}, testState.logs...)
 }
@@ -0,0 +1,368 @@
+package mockhttp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Record creates a new APIMock instance forwarding the unmocked invocations to the upstream API. Each forwarded
+// request and the response of the upstream are saved to the cassette file during test cleanup.
+// Stubs can still be registered, stubbed invocations are neither forwarded nor recorded.
+func Record(testState T, upstreamURL string, cassettePath string, options ...Option) *APIMock {
+	mockedAPI := API(testState, options...)
+	upstream, err := url.Parse(upstreamURL)
+	if err != nil {
+		testState.Fatal(err)
+		return mockedAPI
+	}
+
+	recorder := &cassetteRecorder{
+		forwarded: map[*http.Request]bool{},
+	}
+	proxy := httputil.NewSingleHostReverseProxy(upstream)
+	director := proxy.Director
+	proxy.Director = func(request *http.Request) {
+		director(request)
+		request.Host = upstream.Host
+	}
+	mockedAPI.fallback = func(writer http.ResponseWriter, request *http.Request) {
+		recorder.markForwarded(request)
+		proxy.ServeHTTP(writer, request)
+	}
+
+	testState.Cleanup(func() {
+		err := recorder.cassette(mockedAPI).save(cassettePath)
+		if err != nil {
+			testState.Error(err)
+		}
+	})
+	return mockedAPI
+}
+
+// ReplayOption configures how recorded interactions are replayed. Replay options are options of the mocked API
+// created by Replay or FromHAR and have no effect on the other ones.
+type ReplayOption = Option
+
+// replaySettings are the settings of the recorded interactions replay, see ReplayOption.
+type replaySettings struct {
+	matchBody bool
+	lenient   bool
+	inOrder   bool
+}
+
+// ReplayMatchingBody matches the invocations on their body in addition to their method, path and query.
+// JSON bodies are compared semantically.
+func ReplayMatchingBody() ReplayOption {
+	return func(mockedAPI *APIMock) {
+		mockedAPI.replay.matchBody = true
+	}
+}
+
+// ReplayLeniently responds with a 404 status instead of failing the test when an invocation matches no interaction.
+func ReplayLeniently() ReplayOption {
+	return func(mockedAPI *APIMock) {
+		mockedAPI.replay.lenient = true
+	}
+}
+
+// ReplayInOrder requires the invocations to be made in the order the interactions were recorded.
+func ReplayInOrder() ReplayOption {
+	return func(mockedAPI *APIMock) {
+		mockedAPI.replay.inOrder = true
+	}
+}
+
+// Replay creates a new APIMock instance stubbing the interactions recorded in the cassette file. Invocations are
+// matched on their method, path and query. When several interactions match, they are replayed in the recorded order,
+// the last one being replayed again once all have been consumed. Redacted query values of the cassette match any
+// value. By default, an invocation matching no interaction fails the test. Replayed invocations can be verified as
+// usual. The options are the ones of API along with the replay options (e.g. ReplayInOrder).
+func Replay(testState T, cassettePath string, options ...Option) *APIMock {
+	mockedAPI := API(testState, options...)
+	recorded, err := loadCassette(cassettePath)
+	if err != nil {
+		testState.Fatal(err)
+		return mockedAPI
+	}
+	newCassetteReplayer(mockedAPI, recorded.Interactions)
+	return mockedAPI
+}
+
+// cassetteReplayer serves recorded interactions as stubs of a mocked API.
+type cassetteReplayer struct {
+	replaySettings
+	api          *APIMock
+	interactions []*cassetteInteraction
+	consumed     []bool
+	mu           sync.Mutex
+}
+
+func newCassetteReplayer(mockedAPI *APIMock, interactions []*cassetteInteraction) *cassetteReplayer {
+	replayer := &cassetteReplayer{
+		replaySettings: mockedAPI.replay,
+		api:            mockedAPI,
+		interactions:   interactions,
+		consumed:       make([]bool, len(interactions)),
+	}
+	for _, interaction := range interactions {
+		mockedAPI.Stub(interaction.Request.Method, interaction.Request.Path).With(replayer.serveHTTP)
+	}
+	if replayer.lenient {
+		mockedAPI.fallback = replayer.unmatched
+	}
+	return replayer
+}
+
+func (replayer *cassetteReplayer) serveHTTP(writer http.ResponseWriter, request *http.Request) {
+	interaction := replayer.next(request)
+	if interaction == nil {
+		replayer.unmatched(writer, request)
+		return
+	}
+
+	body, err := decodeCassetteBody(interaction.Response.Body, interaction.Response.BodyEncoding)
+	if err != nil {
+		replayer.api.testState.Error(err)
+	}
+	for name, values := range interaction.Response.Headers {
+		if name == "Content-Length" {
+			continue
+		}
+		for _, value := range values {
+			writer.Header().Add(name, value)
+		}
+	}
+	writer.WriteHeader(interaction.Response.Status)
+	_, err = writer.Write(body)
+	if err != nil {
+		replayer.api.testState.Error(err)
+	}
+}
+
+func (replayer *cassetteReplayer) unmatched(writer http.ResponseWriter, request *http.Request) {
+	writer.WriteHeader(http.StatusNotFound)
+	requestURI := replayer.api.redaction.requestURI(request.URL)
+	if replayer.lenient {
+		replayer.api.testState.Log(fmt.Sprintf("no recorded interaction matching %s %s", request.Method, requestURI))
+		return
+	}
+	replayer.api.testState.Errorf("no recorded interaction matching %s %s", request.Method, requestURI)
+}
+
+// next returns the interaction to replay for the request and marks it as consumed, nil if none matches.
+func (replayer *cassetteReplayer) next(request *http.Request) *cassetteInteraction {
+	replayer.mu.Lock()
+	defer replayer.mu.Unlock()
+
+	if replayer.inOrder {
+		for i, interaction := range replayer.interactions {
+			if replayer.consumed[i] {
+				continue
+			}
+			if !replayer.matches(interaction, request) {
+				return nil
+			}
+			replayer.consumed[i] = true
+			return interaction
+		}
+		return nil
+	}
+
+	lastMatch := -1
+	for i, interaction := range replayer.interactions {
+		if !replayer.matches(interaction, request) {
+			continue
+		}
+		if !replayer.consumed[i] {
+			replayer.consumed[i] = true
+			return interaction
+		}
+		lastMatch = i
+	}
+	if lastMatch < 0 {
+		return nil
+	}
+	return replayer.interactions[lastMatch]
+}
+
+func (replayer *cassetteReplayer) matches(interaction *cassetteInteraction, request *http.Request) bool {
+	if !strings.EqualFold(interaction.Request.Method, request.Method) || interaction.Request.Path != request.URL.Path {
+		return false
+	}
+	expectedQuery, err := url.ParseQuery(interaction.Request.Query)
+	if err != nil || !matchesRecordedQuery(expectedQuery, request.URL.Query()) {
+		return false
+	}
+	if !replayer.matchBody {
+		return true
+	}
+	expectedBody, err := decodeCassetteBody(interaction.Request.Body, interaction.Request.BodyEncoding)
+	if err != nil {
+		return false
+	}
+	actualBody, err := io.ReadAll(request.Body)
+	if err != nil {
+		return false
+	}
+	request.Body = io.NopCloser(bytes.NewReader(actualBody))
+	return equalBodies(expectedBody, actualBody)
+}
+
+// matchesRecordedQuery compares the queries, a redacted recorded value matching any actual value.
+func matchesRecordedQuery(expected url.Values, actual url.Values) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for name, expectedValues := range expected {
+		actualValues := actual[name]
+		if len(expectedValues) != len(actualValues) {
+			return false
+		}
+		for i, expectedValue := range expectedValues {
+			if expectedValue != RedactedValue && expectedValue != actualValues[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// equalBodies compares the bodies semantically when both are JSON, byte per byte otherwise.
+func equalBodies(expected []byte, actual []byte) bool {
+	var expectedJSON, actualJSON any
+	if json.Unmarshal(expected, &expectedJSON) == nil && json.Unmarshal(actual, &actualJSON) == nil {
+		return reflect.DeepEqual(expectedJSON, actualJSON)
+	}
+	return bytes.Equal(expected, actual)
+}
+
+// cassetteRecorder keeps track of the requests forwarded to the upstream API.
+type cassetteRecorder struct {
+	forwarded map[*http.Request]bool
+	mu        sync.Mutex
+}
+
+func (recorder *cassetteRecorder) markForwarded(request *http.Request) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.forwarded[request] = true
+}
+
+// cassette returns the forwarded invocations of the mocked API, in the order they were received.
+func (recorder *cassetteRecorder) cassette(mockedAPI *APIMock) *cassette {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	mockedAPI.mu.Lock()
+	defer mockedAPI.mu.Unlock()
+
+	recorded := &cassette{Interactions: []*cassetteInteraction{}}
+	for _, invocation := range mockedAPI.journal {
+		if recorder.forwarded[invocation.GetRequest()] && invocation.GetResponse() != nil {
+			recorded.Interactions = append(recorded.Interactions, newCassetteInteraction(invocation, mockedAPI.redaction))
+		}
+	}
+	return recorded
+}
+
+// cassette is a set of recorded HTTP interactions.
+type cassette struct {
+	Interactions []*cassetteInteraction `json:"interactions"`
+}
+
+// cassetteInteraction is a recorded request along with the response it received.
+type cassetteInteraction struct {
+	Request  cassetteRequest  `json:"request"`
+	Response cassetteResponse `json:"response"`
+}
+
+type cassetteRequest struct {
+	Method       string      `json:"method"`
+	Path         string      `json:"path"`
+	Query        string      `json:"query,omitempty"`
+	Headers      http.Header `json:"headers,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"bodyEncoding,omitempty"`
+}
+
+type cassetteResponse struct {
+	Status       int         `json:"status"`
+	Headers      http.Header `json:"headers,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"bodyEncoding,omitempty"`
+}
+
+// base64BodyEncoding marks bodies which are not valid UTF-8 text and are then stored base64 encoded.
+const base64BodyEncoding = "base64"
+
+// newCassetteInteraction returns the interaction of the invocation, with its sensitive data redacted. The response is
+// left empty when the invocation has not been responded yet.
+func newCassetteInteraction(invocation *Invocation, redaction *Redaction) *cassetteInteraction {
+	request := invocation.GetRequest()
+	response := invocation.GetResponse()
+	interaction := &cassetteInteraction{
+		Request: cassetteRequest{
+			Method:  request.Method,
+			Path:    request.URL.Path,
+			Query:   redaction.rawQuery(request.URL.RawQuery),
+			Headers: redaction.headers(request.Header),
+		},
+	}
+	interaction.Request.Body, interaction.Request.BodyEncoding = encodeCassetteBody(redaction.body(invocation.GetPayload()))
+	if response != nil {
+		interaction.Response.Status = response.StatusCode
+		interaction.Response.Headers = redaction.headers(response.Header)
+		interaction.Response.Body, interaction.Response.BodyEncoding = encodeCassetteBody(redaction.body(invocation.GetResponsePayload()))
+	}
+	return interaction
+}
+
+func encodeCassetteBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), base64BodyEncoding
+}
+
+func decodeCassetteBody(body string, encoding string) ([]byte, error) {
+	if encoding == base64BodyEncoding {
+		return base64.StdEncoding.DecodeString(body)
+	}
+	return []byte(body), nil
+}
+
+// loadCassette reads the cassette stored at the given path.
+func loadCassette(path string) (*cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	loaded := &cassette{}
+	if err := json.Unmarshal(data, loaded); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	return loaded, nil
+}
+
+// save writes the cassette to the given path, creating the parent directories if needed.
+func (recorded *cassette) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(recorded, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,321 @@
+package mockhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Record(t *testing.T) {
+	t.Parallel()
+
+	newUpstream := func(t *testing.T) *httptest.Server {
+		upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			body, _ := io.ReadAll(request.Body)
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusCreated)
+			_, _ = writer.Write([]byte(`{"path": "` + request.URL.Path + `", "received": "` + string(body) + `"}`))
+		}))
+		t.Cleanup(upstream.Close)
+		return upstream
+	}
+
+	runCleanups := func(testState *testingmock.MockedT) {
+		for _, cleanup := range testState.GetCleanups() {
+			cleanup()
+		}
+	}
+
+	t.Run("forwards unmocked invocations to the upstream", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		upstream := newUpstream(t)
+		testState := testingmock.New(t)
+		mockedAPI := Record(testState, upstream.URL, filepath.Join(t.TempDir(), "cassette.json"))
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		response, err := http.Post(mockedAPI.GetURL().String()+"/users", "text/plain", bytes.NewBufferString("John"))
+
+		// Assert
+		require.NoError(t, err)
+		testState.AssertDidNotFailed()
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		assert := assertions.New(t)
+		assert.Equal(http.StatusCreated, response.StatusCode)
+		assert.JSONEq(`{"path": "/users", "received": "John"}`, string(body))
+		mockedAPI.Verify(http.MethodPost, "/users").HasBeenCalledOnce().WithStringPayload("John")
+	})
+
+	t.Run("saves the forwarded interactions to the cassette on cleanup", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		upstream := newUpstream(t)
+		cassettePath := filepath.Join(t.TempDir(), "fixtures", "cassette.json")
+		testState := testingmock.New(t)
+		mockedAPI := Record(testState, upstream.URL, cassettePath)
+		mockedAPI.Stub(http.MethodGet, "/stubbed").WithStatusCode(http.StatusOK)
+
+		_, err := http.Post(mockedAPI.GetURL().String()+"/users?notify=true", "text/plain", bytes.NewBufferString("John"))
+		require.NoError(t, err)
+		_, err = http.Get(mockedAPI.GetURL().String() + "/stubbed")
+		require.NoError(t, err)
+
+		// Act
+		runCleanups(testState)
+
+		// Assert
+		testState.AssertDidNotFailed()
+		data, err := os.ReadFile(cassettePath)
+		require.NoError(t, err)
+		recorded := cassette{}
+		require.NoError(t, json.Unmarshal(data, &recorded))
+		require.Len(t, recorded.Interactions, 1)
+
+		assert := assertions.New(t)
+		interaction := recorded.Interactions[0]
+		assert.Equal(http.MethodPost, interaction.Request.Method)
+		assert.Equal("/users", interaction.Request.Path)
+		assert.Equal("notify=true", interaction.Request.Query)
+		assert.Equal("John", interaction.Request.Body)
+		assert.Equal("text/plain", interaction.Request.Headers.Get("Content-Type"))
+		assert.Equal(http.StatusCreated, interaction.Response.Status)
+		assert.Equal("application/json", interaction.Response.Headers.Get("Content-Type"))
+		assert.JSONEq(`{"path": "/users", "received": "John"}`, interaction.Response.Body)
+	})
+
+	t.Run("saves the interactions forwarded before invocations are reset", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		upstream := newUpstream(t)
+		cassettePath := filepath.Join(t.TempDir(), "cassette.json")
+		testState := testingmock.New(t)
+		mockedAPI := Record(testState, upstream.URL, cassettePath)
+		_, err := http.Post(mockedAPI.GetURL().String()+"/users", "text/plain", bytes.NewBufferString("John"))
+		require.NoError(t, err)
+		mockedAPI.ResetInvocations()
+
+		// Act
+		runCleanups(testState)
+
+		// Assert
+		testState.AssertDidNotFailed()
+		data, err := os.ReadFile(cassettePath)
+		require.NoError(t, err)
+		recorded := cassette{}
+		require.NoError(t, json.Unmarshal(data, &recorded))
+		require.Len(t, recorded.Interactions, 1)
+		mockedAPI.Verify(http.MethodPost, "/users").HasNotBeenCalled()
+	})
+
+	t.Run("stores binary bodies base64 encoded", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		upstream := newUpstream(t)
+		cassettePath := filepath.Join(t.TempDir(), "cassette.json")
+		testState := testingmock.New(t)
+		mockedAPI := Record(testState, upstream.URL, cassettePath)
+
+		_, err := http.Post(mockedAPI.GetURL().String()+"/binary", "application/octet-stream", bytes.NewReader([]byte{0xff, 0xfe}))
+		require.NoError(t, err)
+
+		// Act
+		runCleanups(testState)
+
+		// Assert
+		data, err := os.ReadFile(cassettePath)
+		require.NoError(t, err)
+		recorded := cassette{}
+		require.NoError(t, json.Unmarshal(data, &recorded))
+		require.Len(t, recorded.Interactions, 1)
+		assertions.Equal(t, "//4=", recorded.Interactions[0].Request.Body)
+		assertions.Equal(t, base64BodyEncoding, recorded.Interactions[0].Request.BodyEncoding)
+	})
+}
+
+func Test_Replay(t *testing.T) {
+	t.Parallel()
+
+	writeCassette := func(t *testing.T, interactions ...*cassetteInteraction) string {
+		cassettePath := filepath.Join(t.TempDir(), "cassette.json")
+		require.NoError(t, (&cassette{Interactions: interactions}).save(cassettePath))
+		return cassettePath
+	}
+
+	interaction := func(method string, path string, query string, requestBody string, status int, responseBody string) *cassetteInteraction {
+		return &cassetteInteraction{
+			Request: cassetteRequest{Method: method, Path: path, Query: query, Body: requestBody},
+			Response: cassetteResponse{
+				Status:  status,
+				Headers: http.Header{"Content-Type": []string{"text/plain"}},
+				Body:    responseBody,
+			},
+		}
+	}
+
+	call := func(t *testing.T, mockedAPI *APIMock, method string, pathAndQuery string, body string) (int, string) {
+		request, err := http.NewRequest(method, mockedAPI.GetURL().String()+pathAndQuery, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		responseBody, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response.StatusCode, string(responseBody)
+	}
+
+	t.Run("replays the interaction matching method, path and query", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		cassettePath := writeCassette(t,
+			interaction(http.MethodGet, "/users", "page=1", "", http.StatusOK, "page 1"),
+			interaction(http.MethodGet, "/users", "page=2", "", http.StatusOK, "page 2"),
+		)
+		testState := testingmock.New(t)
+		mockedAPI := Replay(testState, cassettePath)
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		status, body := call(t, mockedAPI, http.MethodGet, "/users?page=2", "")
+
+		// Assert
+		testState.AssertDidNotFailed()
+		assertions.Equal(t, http.StatusOK, status)
+		assertions.Equal(t, "page 2", body)
+		mockedAPI.Verify(http.MethodGet, "/users").HasBeenCalledOnce().WithQueryValue("page", "2")
+	})
+
+	t.Run("replays matching interactions in sequence then repeats the last one", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		cassettePath := writeCassette(t,
+			interaction(http.MethodPost, "/jobs", "", "", http.StatusAccepted, "pending"),
+			interaction(http.MethodPost, "/jobs", "", "", http.StatusOK, "done"),
+		)
+		testState := testingmock.New(t)
+		mockedAPI := Replay(testState, cassettePath)
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		_, first := call(t, mockedAPI, http.MethodPost, "/jobs", "")
+		_, second := call(t, mockedAPI, http.MethodPost, "/jobs", "")
+		_, third := call(t, mockedAPI, http.MethodPost, "/jobs", "")
+
+		// Assert
+		testState.AssertDidNotFailed()
+		assert := assertions.New(t)
+		assert.Equal("pending", first)
+		assert.Equal("done", second)
+		assert.Equal("done", third)
+	})
+
+	t.Run("matches the body when requested", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		cassettePath := writeCassette(t,
+			interaction(http.MethodPost, "/users", "", `{"name": "John"}`, http.StatusCreated, "John"),
+			interaction(http.MethodPost, "/users", "", `{"name": "Jane"}`, http.StatusCreated, "Jane"),
+		)
+		testState := testingmock.New(t)
+		mockedAPI := Replay(testState, cassettePath, ReplayMatchingBody())
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		_, body := call(t, mockedAPI, http.MethodPost, "/users", `{ "name":"Jane" }`)
+
+		// Assert
+		testState.AssertDidNotFailed()
+		assertions.Equal(t, "Jane", body)
+	})
+
+	t.Run("fails when no interaction matches", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		cassettePath := writeCassette(t, interaction(http.MethodGet, "/users", "page=1", "", http.StatusOK, "page 1"))
+		testState := testingmock.New(t)
+		mockedAPI := Replay(testState, cassettePath)
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		status, _ := call(t, mockedAPI, http.MethodGet, "/users?page=3", "")
+
+		// Assert
+		testState.AssertFailedWithErrorMessage("no recorded interaction matching GET /users?page=3")
+		assertions.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("redacts the query of the unmatched invocation", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		cassettePath := writeCassette(t, interaction(http.MethodGet, "/users", "page=1", "", http.StatusOK, "page 1"))
+		testState := testingmock.New(t)
+		mockedAPI := Replay(testState, cassettePath, WithRedaction(Redaction{QueryParams: []string{"token"}}), ReplayInOrder())
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		status, _ := call(t, mockedAPI, http.MethodGet, "/users?token=secret", "")
+
+		// Assert
+		testState.AssertFailedWithErrorMessage("no recorded interaction matching GET /users?token=%5BREDACTED%5D")
+		assertions.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("responds not found without failing when lenient", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		cassettePath := writeCassette(t, interaction(http.MethodGet, "/users", "", "", http.StatusOK, "users"))
+		testState := testingmock.New(t)
+		mockedAPI := Replay(testState, cassettePath, ReplayLeniently())
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		unknownQueryStatus, _ := call(t, mockedAPI, http.MethodGet, "/users?page=3", "")
+		unknownPathStatus, _ := call(t, mockedAPI, http.MethodGet, "/unknown", "")
+
+		// Assert
+		testState.AssertDidNotFailed()
+		assertions.Equal(t, http.StatusNotFound, unknownQueryStatus)
+		assertions.Equal(t, http.StatusNotFound, unknownPathStatus)
+	})
+
+	t.Run("fails when invocations are out of order", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		cassettePath := writeCassette(t,
+			interaction(http.MethodPost, "/login", "", "", http.StatusOK, "token"),
+			interaction(http.MethodGet, "/profile", "", "", http.StatusOK, "profile"),
+		)
+		testState := testingmock.New(t)
+		mockedAPI := Replay(testState, cassettePath, ReplayInOrder())
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		status, _ := call(t, mockedAPI, http.MethodGet, "/profile", "")
+
+		// Assert
+		testState.AssertFailedWithError()
+		assertions.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("fails when the cassette cannot be read", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+
+		// Act
+		mockedAPI := Replay(testState, filepath.Join(t.TempDir(), "missing.json"))
+		t.Cleanup(mockedAPI.Close)
+
+		// Assert
+		testState.AssertFailedWithFatal()
+	})
+}
@@ -0,0 +1,199 @@
+// Command gomockhttp starts a standalone mock HTTP server serving the stubs declared in stub files, so that services
+// running in separate processes can use the same stubs as the Go tests.
+//
+// Usage:
+//
+//	gomockhttp -addr :8080 -stubs testdata/stubs [-stubs more-stubs.yaml] [-inspect-addr :8081] [-admin]
+//
+// Each invocation is logged along with the status code of its response. When an inspection address is given, the
+// invocations received so far are served as an HTTP Archive (HAR) on GET /invocations, along with the admin API
+// (see mockhttp.APIMock.AdminHandler) under /__admin. The -admin flag exposes the admin API on the mock server too.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/le-yams/gomockhttp"
+)
+
+func main() {
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	server, err := start(os.Args[1:], logger)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	<-ctx.Done()
+	stop()
+	server.close()
+}
+
+// mockServer is a running mock server along with its optional inspection server.
+type mockServer struct {
+	state      *processState
+	api        *mockhttp.APIMock
+	inspection *http.Server
+	// inspectionAddress is the address the inspection server listens on, empty when disabled.
+	inspectionAddress string
+}
+
+// start parses the command line arguments and starts the mock server they describe.
+func start(args []string, logger *log.Logger) (*mockServer, error) {
+	flags := flag.NewFlagSet("gomockhttp", flag.ContinueOnError)
+	address := flags.String("addr", ":8080", "address the mock server listens on")
+	inspectAddress := flags.String("inspect-addr", "", "address of the server exposing the invocations, disabled when empty")
+	admin := flags.Bool("admin", false, "expose the admin API under /__admin on the mock server")
+	stubs := stubPaths{}
+	flags.Var(&stubs, "stubs", "stub file or directory of stub files to load, can be repeated")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if len(stubs) == 0 {
+		return nil, errors.New("at least one -stubs file or directory is required")
+	}
+
+	state := &processState{logger: logger}
+	options := []mockhttp.Option{mockhttp.WithAddress(*address), mockhttp.WithInvocationLogging()}
+	if *admin {
+		options = append(options, mockhttp.WithAdminAPI())
+	}
+	server := &mockServer{
+		state: state,
+		api:   mockhttp.API(state, options...),
+	}
+	for _, path := range stubs {
+		server.api.LoadStubs(path)
+	}
+	if state.Failed() {
+		server.close()
+		return nil, errors.New("unable to start the mock server")
+	}
+	logger.Printf("mock server listening on %s", server.api.GetURL())
+
+	if *inspectAddress != "" {
+		listener, err := net.Listen("tcp", *inspectAddress)
+		if err != nil {
+			server.close()
+			return nil, err
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /invocations", func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("Content-Type", "application/json")
+			if err := server.api.ExportHAR(writer); err != nil {
+				logger.Print(err)
+			}
+		})
+		mux.Handle("/__admin/", server.api.AdminHandler())
+		server.inspection = &http.Server{Handler: mux}
+		server.inspectionAddress = listener.Addr().String()
+		go func() {
+			if err := server.inspection.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+				logger.Print(err)
+			}
+		}()
+		logger.Printf("invocations available on http://%s/invocations", server.inspectionAddress)
+	}
+	return server, nil
+}
+
+// close stops the mock server and its inspection server.
+func (server *mockServer) close() {
+	if server.inspection != nil {
+		_ = server.inspection.Close()
+	}
+	server.state.cleanup()
+}
+
+// stubPaths are the stub files and directories given on the command line.
+type stubPaths []string
+
+func (paths *stubPaths) String() string {
+	return strings.Join(*paths, ",")
+}
+
+func (paths *stubPaths) Set(path string) error {
+	*paths = append(*paths, path)
+	return nil
+}
+
+// processState is the test state of the standalone mock server: failures, such as unmocked invocations, are logged
+// instead of stopping the process.
+type processState struct {
+	logger   *log.Logger
+	failed   bool
+	cleanups []func()
+	mu       sync.Mutex
+}
+
+func (state *processState) Error(args ...any) {
+	state.fail(fmt.Sprint(args...))
+}
+
+func (state *processState) Errorf(format string, args ...any) {
+	state.fail(fmt.Sprintf(format, args...))
+}
+
+func (state *processState) Fatal(args ...any) {
+	state.fail(fmt.Sprint(args...))
+}
+
+func (state *processState) Fatalf(format string, args ...any) {
+	state.fail(fmt.Sprintf(format, args...))
+}
+
+func (state *processState) FailNow() {
+	state.fail("failed")
+}
+
+func (state *processState) Log(args ...any) {
+	state.logger.Print(args...)
+}
+
+func (state *processState) Logf(format string, args ...any) {
+	state.logger.Printf(format, args...)
+}
+
+func (state *processState) Failed() bool {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.failed
+}
+
+func (state *processState) Cleanup(cleanup func()) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.cleanups = append(state.cleanups, cleanup)
+}
+
+func (state *processState) fail(message string) {
+	state.mu.Lock()
+	state.failed = true
+	state.mu.Unlock()
+	state.logger.Print("ERROR ", strings.TrimSuffix(message, "\n"))
+}
+
+// cleanup runs the registered cleanups in the reverse order, as a test would do.
+func (state *processState) cleanup() {
+	state.mu.Lock()
+	cleanups := state.cleanups
+	state.cleanups = nil
+	state.mu.Unlock()
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
+	}
+}
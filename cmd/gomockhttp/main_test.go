@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_gomockhttp_should(t *testing.T) {
+	t.Parallel()
+
+	writeStubs := func(t *testing.T) string {
+		path := filepath.Join(t.TempDir(), "stubs.yaml")
+		stubs := "stubs:\n  - method: GET\n    path: /users/{id}\n    response:\n      json: {name: John}\n"
+		require.NoError(t, os.WriteFile(path, []byte(stubs), 0o600))
+		return path
+	}
+
+	t.Run("serve the stubs and log the invocations", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		output := &syncBuffer{}
+		server, err := start([]string{"-addr", "127.0.0.1:0", "-stubs", writeStubs(t)}, log.New(output, "", 0))
+		require.NoError(t, err)
+		t.Cleanup(server.close)
+
+		// Act
+		response, err := http.Get(server.api.GetURL().JoinPath("users", "42").String())
+		require.NoError(t, err)
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		unmocked, err := http.Get(server.api.GetURL().JoinPath("orders").String())
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.JSONEq(t, `{"name": "John"}`, string(body))
+		assert.Equal(t, http.StatusNotFound, unmocked.StatusCode)
+		assert.Eventually(t, func() bool {
+			return strings.Contains(output.String(), "GET /users/42 200") &&
+				strings.Contains(output.String(), "ERROR unmocked invocation get /orders")
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("expose the invocations for inspection", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		server, err := start([]string{
+			"-addr", "127.0.0.1:0",
+			"-inspect-addr", "127.0.0.1:0",
+			"-stubs", writeStubs(t),
+		}, log.New(io.Discard, "", 0))
+		require.NoError(t, err)
+		t.Cleanup(server.close)
+		_, err = http.Get(server.api.GetURL().JoinPath("users", "42").String())
+		require.NoError(t, err)
+
+		// Act
+		response, err := http.Get("http://" + server.inspectionAddress + "/invocations")
+		require.NoError(t, err)
+
+		// Assert
+		archive := struct {
+			Log struct {
+				Entries []struct {
+					Request struct {
+						Method string `json:"method"`
+						URL    string `json:"url"`
+					} `json:"request"`
+				} `json:"entries"`
+			} `json:"log"`
+		}{}
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&archive))
+		require.Len(t, archive.Log.Entries, 1)
+		assert.Equal(t, http.MethodGet, archive.Log.Entries[0].Request.Method)
+		assert.Equal(t, server.api.GetURL().String()+"/users/42", archive.Log.Entries[0].Request.URL)
+	})
+
+	t.Run("expose the admin API", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		server, err := start([]string{
+			"-addr", "127.0.0.1:0",
+			"-admin",
+			"-stubs", writeStubs(t),
+		}, log.New(io.Discard, "", 0))
+		require.NoError(t, err)
+		t.Cleanup(server.close)
+		stub := `{"method": "GET", "path": "/health", "response": {"status": 204}}`
+
+		// Act
+		response, err := http.Post(server.api.GetURL().JoinPath("__admin", "stubs").String(), "application/json", strings.NewReader(stub))
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+		health, err := http.Get(server.api.GetURL().JoinPath("health").String())
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, health.StatusCode)
+	})
+
+	t.Run("fail when no stubs are given", func(t *testing.T) {
+		t.Parallel()
+		// Act
+		_, err := start([]string{"-addr", "127.0.0.1:0"}, log.New(io.Discard, "", 0))
+
+		// Assert
+		assert.Error(t, err)
+	})
+
+	t.Run("fail when the stubs cannot be loaded", func(t *testing.T) {
+		t.Parallel()
+		// Act
+		_, err := start([]string{
+			"-addr", "127.0.0.1:0",
+			"-stubs", filepath.Join(t.TempDir(), "missing.yaml"),
+		}, log.New(io.Discard, "", 0))
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+// syncBuffer is a buffer safe for concurrent use, the server logging from its own goroutines.
+type syncBuffer struct {
+	buffer bytes.Buffer
+	mu     sync.Mutex
+}
+
+func (buffer *syncBuffer) Write(data []byte) (int, error) {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	return buffer.buffer.Write(data)
+}
+
+func (buffer *syncBuffer) String() string {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	return buffer.buffer.String()
+}
@@ -0,0 +1,227 @@
+package mockhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// activeCoverage is the collector enabled by EnableCoverage, nil when coverage is not collected.
+var activeCoverage atomic.Pointer[Coverage]
+
+// Coverage collects, across all the API mocks of a test run, which stubs and which OpenAPI operations (of the
+// documents given to FromOpenAPI or WithOpenAPIValidation) have been invoked.
+type Coverage struct {
+	stubs map[string]*CoverageEndpoint
+	specs map[string]map[string]*CoverageEndpoint
+	mu    sync.Mutex
+}
+
+// EnableCoverage starts collecting the coverage of the API mocks, typically from TestMain. The report can then be
+// written once the tests have run:
+//
+//	func TestMain(m *testing.M) {
+//		coverage := mockhttp.EnableCoverage()
+//		code := m.Run()
+//		_ = coverage.WriteReport(os.Stdout)
+//		os.Exit(code)
+//	}
+func EnableCoverage() *Coverage {
+	coverage := &Coverage{
+		stubs: map[string]*CoverageEndpoint{},
+		specs: map[string]map[string]*CoverageEndpoint{},
+	}
+	activeCoverage.Store(coverage)
+	return coverage
+}
+
+// CoverageEndpoint is a stub or an OpenAPI operation, along with its number of invocations.
+type CoverageEndpoint struct {
+	// API is the name of the mocked API of the stub (see WithName), empty for the unnamed mocks and the operations.
+	API    string `json:"api,omitempty"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Host is the virtual host of the stub (see APIMock.Host), empty for the stubs of any host and the operations.
+	Host        string `json:"host,omitempty"`
+	Invocations int    `json:"invocations"`
+}
+
+// CoverageReport is the coverage of the stubs and of the OpenAPI operations.
+type CoverageReport struct {
+	Stubs CoverageSection   `json:"stubs"`
+	Specs []CoverageSection `json:"specs"`
+}
+
+// CoverageSection is the coverage of a set of endpoints, either the stubs or the operations of an OpenAPI document.
+type CoverageSection struct {
+	// Name is the title and version of the OpenAPI document, empty for the stubs.
+	Name      string              `json:"name,omitempty"`
+	Total     int                 `json:"total"`
+	Invoked   int                 `json:"invoked"`
+	Endpoints []*CoverageEndpoint `json:"endpoints"`
+}
+
+// Report returns the coverage collected so far.
+func (coverage *Coverage) Report() *CoverageReport {
+	coverage.mu.Lock()
+	defer coverage.mu.Unlock()
+
+	report := &CoverageReport{
+		Stubs: newCoverageSection("", coverage.stubs),
+		Specs: []CoverageSection{},
+	}
+	for name, operations := range coverage.specs {
+		report.Specs = append(report.Specs, newCoverageSection(name, operations))
+	}
+	sort.Slice(report.Specs, func(i, j int) bool {
+		return report.Specs[i].Name < report.Specs[j].Name
+	})
+	return report
+}
+
+// WriteReport writes the coverage collected so far as text, listing the endpoints never invoked.
+func (coverage *Coverage) WriteReport(writer io.Writer) error {
+	report := coverage.Report()
+	text := &strings.Builder{}
+	report.Stubs.writeText(text, "stubs")
+	for _, spec := range report.Specs {
+		spec.writeText(text, fmt.Sprintf("OpenAPI %q operations", spec.Name))
+	}
+	_, err := io.WriteString(writer, text.String())
+	return err
+}
+
+// WriteJSONReport writes the coverage collected so far as JSON.
+func (coverage *Coverage) WriteJSONReport(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(coverage.Report())
+}
+
+func newCoverageSection(name string, endpoints map[string]*CoverageEndpoint) CoverageSection {
+	section := CoverageSection{
+		Name:      name,
+		Endpoints: []*CoverageEndpoint{},
+	}
+	for _, endpoint := range endpoints {
+		copied := *endpoint
+		section.Endpoints = append(section.Endpoints, &copied)
+		section.Total++
+		if endpoint.Invocations > 0 {
+			section.Invoked++
+		}
+	}
+	sort.Slice(section.Endpoints, func(i, j int) bool {
+		if section.Endpoints[i].API != section.Endpoints[j].API {
+			return section.Endpoints[i].API < section.Endpoints[j].API
+		}
+		if section.Endpoints[i].Path != section.Endpoints[j].Path {
+			return section.Endpoints[i].Path < section.Endpoints[j].Path
+		}
+		if section.Endpoints[i].Method != section.Endpoints[j].Method {
+			return section.Endpoints[i].Method < section.Endpoints[j].Method
+		}
+		return section.Endpoints[i].Host < section.Endpoints[j].Host
+	})
+	return section
+}
+
+func (section *CoverageSection) writeText(text *strings.Builder, title string) {
+	percentage := 100.0
+	if section.Total > 0 {
+		percentage = 100 * float64(section.Invoked) / float64(section.Total)
+	}
+	_, _ = fmt.Fprintf(text, "%s: %d/%d invoked (%.1f%%)\n", title, section.Invoked, section.Total, percentage)
+	for _, endpoint := range section.Endpoints {
+		api := ""
+		if endpoint.API != "" {
+			api = endpoint.API + ": "
+		}
+		if endpoint.Invocations > 0 {
+			_, _ = fmt.Fprintf(text, "  [x] %s%s %s%s (%d invocations)\n", api, endpoint.Method, endpoint.Host, endpoint.Path, endpoint.Invocations)
+		} else {
+			_, _ = fmt.Fprintf(text, "  [ ] %s%s %s%s\n", api, endpoint.Method, endpoint.Host, endpoint.Path)
+		}
+	}
+}
+
+// addSpec keeps track of an OpenAPI document of the mocked API, whose operations coverage is collected.
+func (mockedAPI *APIMock) addSpec(document *openAPIDocument) {
+	mockedAPI.specs = append(mockedAPI.specs, document)
+	activeCoverage.Load().registerSpec(document)
+}
+
+func (coverage *Coverage) registerStub(api string, call HTTPCall) {
+	if coverage == nil {
+		return
+	}
+	coverage.mu.Lock()
+	defer coverage.mu.Unlock()
+	coverage.endpoint(coverage.stubs, api, call.Method, call.Path, call.Host)
+}
+
+func (coverage *Coverage) recordStubInvocation(api string, call HTTPCall) {
+	if coverage == nil {
+		return
+	}
+	coverage.mu.Lock()
+	defer coverage.mu.Unlock()
+	coverage.endpoint(coverage.stubs, api, call.Method, call.Path, call.Host).Invocations++
+}
+
+func (coverage *Coverage) registerSpec(document *openAPIDocument) {
+	if coverage == nil {
+		return
+	}
+	coverage.mu.Lock()
+	defer coverage.mu.Unlock()
+	operations := coverage.specOperations(document)
+	for path, item := range document.Paths {
+		for method := range item.operations() {
+			coverage.endpoint(operations, "", method, path, "")
+		}
+	}
+}
+
+func (coverage *Coverage) recordOperationInvocation(document *openAPIDocument, request *http.Request) {
+	if coverage == nil {
+		return
+	}
+	match := document.findOperation(request.Method, request.URL.Path)
+	if match == nil {
+		return
+	}
+	coverage.mu.Lock()
+	defer coverage.mu.Unlock()
+	coverage.endpoint(coverage.specOperations(document), "", request.Method, match.path, "").Invocations++
+}
+
+// specOperations returns the operations of the document, documents being identified by their title and version.
+// It must be called while holding the coverage lock.
+func (coverage *Coverage) specOperations(document *openAPIDocument) map[string]*CoverageEndpoint {
+	name := strings.TrimSpace(document.Info.Title + " " + document.Info.Version)
+	operations, ok := coverage.specs[name]
+	if !ok {
+		operations = map[string]*CoverageEndpoint{}
+		coverage.specs[name] = operations
+	}
+	return operations
+}
+
+// endpoint returns the endpoint of the given mocked API name, method, path and virtual host, adding it when missing.
+// It must be called while holding the coverage lock.
+func (coverage *Coverage) endpoint(endpoints map[string]*CoverageEndpoint, api string, method string, path string, host string) *CoverageEndpoint {
+	method = strings.ToUpper(method)
+	key := api + " " + method + " " + host + path
+	endpoint, ok := endpoints[key]
+	if !ok {
+		endpoint = &CoverageEndpoint{API: api, Method: method, Path: path, Host: host}
+		endpoints[key] = endpoint
+	}
+	return endpoint
+}
@@ -0,0 +1,173 @@
+package mockhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test_Coverage is not parallel as coverage is collected across all the API mocks.
+func Test_Coverage(t *testing.T) {
+	t.Run("should collect the stubs and operations invoked across API mocks", func(t *testing.T) {
+		// Arrange
+		coverage := EnableCoverage()
+		t.Cleanup(func() { activeCoverage.Store(nil) })
+
+		firstAPI := API(testingmock.New(t))
+		t.Cleanup(firstAPI.Close)
+		firstAPI.
+			Stub(http.MethodGet, "/users/{id}").WithStatusCode(http.StatusOK).
+			Stub(http.MethodDelete, "/users/{id}").WithStatusCode(http.StatusNoContent)
+		secondAPI := API(testingmock.New(t))
+		t.Cleanup(secondAPI.Close)
+		secondAPI.Stub(http.MethodGet, "/users/{id}").WithStatusCode(http.StatusOK)
+		petstoreAPI := FromOpenAPI(testingmock.New(t), []byte(petstoreSpec))
+		t.Cleanup(petstoreAPI.Close)
+
+		// Act
+		for _, url := range []string{
+			firstAPI.GetURL().String() + "/users/1",
+			secondAPI.GetURL().String() + "/users/2",
+			petstoreAPI.GetURL().String() + "/v1/pets",
+		} {
+			_, err := http.Get(url)
+			require.NoError(t, err)
+		}
+
+		// Assert
+		report := coverage.Report()
+		assert := assertions.New(t)
+		assert.Contains(report.Stubs.Endpoints, &CoverageEndpoint{Method: "GET", Path: "/users/{id}", Invocations: 2})
+		assert.Contains(report.Stubs.Endpoints, &CoverageEndpoint{Method: "DELETE", Path: "/users/{id}"})
+		assert.Contains(report.Stubs.Endpoints, &CoverageEndpoint{Method: "GET", Path: "/v1/pets", Invocations: 1})
+		require.Len(t, report.Specs, 1)
+		spec := report.Specs[0]
+		assert.Equal("Petstore 1.0.0", spec.Name)
+		assert.Equal(1, spec.Invoked)
+		assert.Contains(spec.Endpoints, &CoverageEndpoint{Method: "GET", Path: "/pets", Invocations: 1})
+		assert.Contains(spec.Endpoints, &CoverageEndpoint{Method: "POST", Path: "/pets"})
+	})
+
+	t.Run("should collect the stubs of each virtual host", func(t *testing.T) {
+		// Arrange
+		coverage := EnableCoverage()
+		t.Cleanup(func() { activeCoverage.Store(nil) })
+		mockedAPI := API(testingmock.New(t))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Host("api.example.com").Stub(http.MethodGet, "/users").WithStatusCode(http.StatusOK)
+		mockedAPI.Host("admin.example.com").Stub(http.MethodGet, "/users").WithStatusCode(http.StatusOK)
+		request, err := http.NewRequest(http.MethodGet, mockedAPI.GetURL().String()+"/users", nil)
+		require.NoError(t, err)
+		request.Host = "api.example.com"
+
+		// Act
+		_, err = http.DefaultClient.Do(request)
+		require.NoError(t, err)
+
+		// Assert
+		text := &bytes.Buffer{}
+		require.NoError(t, coverage.WriteReport(text))
+		assert := assertions.New(t)
+		assert.Equal("stubs: 1/2 invoked (50.0%)\n"+
+			"  [ ] GET admin.example.com/users\n"+
+			"  [x] GET api.example.com/users (1 invocations)\n", text.String())
+	})
+
+	t.Run("should collect the stubs of each named API separately", func(t *testing.T) {
+		// Arrange
+		coverage := EnableCoverage()
+		t.Cleanup(func() { activeCoverage.Store(nil) })
+		paymentsAPI := API(testingmock.New(t), WithName("payments"))
+		t.Cleanup(paymentsAPI.Close)
+		paymentsAPI.Stub(http.MethodGet, "/health").WithStatusCode(http.StatusOK)
+		accountsAPI := API(testingmock.New(t), WithName("accounts"))
+		t.Cleanup(accountsAPI.Close)
+		accountsAPI.Stub(http.MethodGet, "/health").WithStatusCode(http.StatusOK)
+
+		// Act
+		_, err := http.Get(paymentsAPI.GetURL().String() + "/health")
+		require.NoError(t, err)
+
+		// Assert
+		text := &bytes.Buffer{}
+		require.NoError(t, coverage.WriteReport(text))
+		assert := assertions.New(t)
+		assert.Equal("stubs: 1/2 invoked (50.0%)\n"+
+			"  [ ] accounts: GET /health\n"+
+			"  [x] payments: GET /health (1 invocations)\n", text.String())
+		assert.Contains(coverage.Report().Stubs.Endpoints,
+			&CoverageEndpoint{API: "payments", Method: "GET", Path: "/health", Invocations: 1})
+	})
+
+	t.Run("should collect each GraphQL operation stub once", func(t *testing.T) {
+		// Arrange
+		coverage := EnableCoverage()
+		t.Cleanup(func() { activeCoverage.Store(nil) })
+		mockedAPI := API(testingmock.New(t))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.StubGraphQL("/graphql").Operation("ListUsers").WithData([]any{})
+
+		// Act
+		_, err := http.Post(mockedAPI.GetURL().String()+"/graphql", "application/graphql",
+			strings.NewReader("query ListUsers { users { name } }"))
+		require.NoError(t, err)
+
+		// Assert
+		text := &bytes.Buffer{}
+		require.NoError(t, coverage.WriteReport(text))
+		assertions.Equal(t, "stubs: 1/1 invoked (100.0%)\n"+
+			"  [x] ANY /graphql (1 invocations)\n", text.String())
+	})
+
+	t.Run("should write the text and JSON reports", func(t *testing.T) {
+		// Arrange
+		coverage := EnableCoverage()
+		t.Cleanup(func() { activeCoverage.Store(nil) })
+		mockedAPI := API(testingmock.New(t))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.
+			Stub(http.MethodGet, "/users").WithStatusCode(http.StatusOK).
+			Stub(http.MethodPost, "/users").WithStatusCode(http.StatusCreated)
+		_, err := http.Get(mockedAPI.GetURL().String() + "/users")
+		require.NoError(t, err)
+
+		// Act
+		text := &bytes.Buffer{}
+		textErr := coverage.WriteReport(text)
+		output := &bytes.Buffer{}
+		jsonErr := coverage.WriteJSONReport(output)
+
+		// Assert
+		require.NoError(t, textErr)
+		require.NoError(t, jsonErr)
+		assert := assertions.New(t)
+		assert.Equal("stubs: 1/2 invoked (50.0%)\n"+
+			"  [x] GET /users (1 invocations)\n"+
+			"  [ ] POST /users\n", text.String())
+		report := &CoverageReport{}
+		require.NoError(t, json.Unmarshal(output.Bytes(), report))
+		assert.Equal(2, report.Stubs.Total)
+		assert.Equal(1, report.Stubs.Invoked)
+		assert.Empty(report.Specs)
+	})
+
+	t.Run("should not collect anything when not enabled", func(t *testing.T) {
+		// Arrange
+		coverage := EnableCoverage()
+		activeCoverage.Store(nil)
+		mockedAPI := API(testingmock.New(t))
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		mockedAPI.Stub(http.MethodGet, "/users").WithStatusCode(http.StatusOK)
+
+		// Assert
+		assertions.New(t).Empty(coverage.Report().Stubs.Endpoints)
+	})
+}
@@ -0,0 +1,253 @@
+package mockhttp
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// GraphQLStub is a helper to stub the operations of a GraphQL endpoint, see APIMock.StubGraphQL.
+type GraphQLStub struct {
+	api  *APIMock
+	path string
+}
+
+// GraphQLStubBuilder is a helper to build the stub of a GraphQL operation, see GraphQLStub.Operation.
+type GraphQLStubBuilder struct {
+	endpoint      *GraphQLStub
+	operationName string
+	variables     map[string]any
+}
+
+// GraphQLVerifier is a helper to verify invocations of a specific GraphQL operation, see APIMock.VerifyGraphQL.
+type GraphQLVerifier struct {
+	api           *APIMock
+	operationName string
+}
+
+// GraphQLError is an error of a GraphQL response, see GraphQLStubBuilder.WithErrors.
+type GraphQLError struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// graphQLRequest is a GraphQL request, sent as a JSON body (or as an application/graphql one) with POST or as query
+// parameters with GET.
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+var graphQLNamePattern = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// graphQLTokenPattern matches the block strings, strings, comments, braces, parentheses and names of a GraphQL
+// document, strings being matched first so that a # they contain does not start a comment.
+var graphQLTokenPattern = regexp.MustCompile(`"""(?:\\"""|[\s\S])*?"""|"(?:\\.|[^"\\\n])*"|#[^\n]*|[{}()]|[_A-Za-z][_0-9A-Za-z]*`)
+
+// StubGraphQL creates a new helper to stub the operations of the GraphQL endpoint at the given path. The operations
+// are told apart by their name, read from the "operationName" field of the request or else from the query document,
+// the requests being accepted with POST as well as with GET.
+func (mockedAPI *APIMock) StubGraphQL(path string) *GraphQLStub {
+	return &GraphQLStub{
+		api:  mockedAPI,
+		path: path,
+	}
+}
+
+// VerifyGraphQL creates a new GraphQLVerifier instance for the GraphQL operation of the given name, whatever the
+// path of the endpoint called.
+func (mockedAPI *APIMock) VerifyGraphQL(operationName string) *GraphQLVerifier {
+	return &GraphQLVerifier{
+		api:           mockedAPI,
+		operationName: operationName,
+	}
+}
+
+// Operation creates a new stub builder for the operation of the given name.
+func (endpoint *GraphQLStub) Operation(operationName string) *GraphQLStubBuilder {
+	return &GraphQLStubBuilder{
+		endpoint:      endpoint,
+		operationName: operationName,
+	}
+}
+
+// WithVariables restricts the stub to the requests whose variables contain the given ones. Several stubs can then be
+// registered for the same operation, the most recently registered stub accepting the request handling it.
+func (stub *GraphQLStubBuilder) WithVariables(variables map[string]any) *GraphQLStubBuilder {
+	stub.variables = variables
+	return stub
+}
+
+// WithData creates a new stub handler returning the given data as a successful GraphQL response.
+func (stub *GraphQLStubBuilder) WithData(data any) *GraphQLStub {
+	return stub.WithResponse(http.StatusOK, map[string]any{"data": data})
+}
+
+// WithErrors creates a new stub handler returning a GraphQL response made of the given errors, without data.
+func (stub *GraphQLStubBuilder) WithErrors(errors ...GraphQLError) *GraphQLStub {
+	return stub.WithResponse(http.StatusOK, map[string]any{"data": nil, "errors": errors})
+}
+
+// WithResponse creates a new stub handler returning the specified status code and JSON response.
+func (stub *GraphQLStubBuilder) WithResponse(statusCode int, response any) *GraphQLStub {
+	var expectedVariables map[string]any
+	if stub.variables != nil {
+		normalized, err := normalizeJSON(stub.variables)
+		if err != nil {
+			stub.endpoint.api.testState.Fatal(err)
+		}
+		expectedVariables, _ = normalized.(map[string]any)
+	}
+	matcher := func(request *http.Request, payload []byte) bool {
+		if request.Method != http.MethodPost && request.Method != http.MethodGet {
+			return false
+		}
+		graphQL, ok := parseGraphQLRequest(request, payload)
+		return ok && graphQL.operation() == stub.operationName && containsVariables(graphQL.Variables, expectedVariables)
+	}
+	// a single stub handles both methods, so that the coverage reports the operation once
+	stub.endpoint.api.Stub(anyMethod, stub.endpoint.path).Matching(matcher).WithJSON(statusCode, response)
+	return stub.endpoint
+}
+
+// HasBeenCalled asserts that the GraphQL operation has been called the expected number of times.
+// It returns all invocations of the operation.
+func (verifier *GraphQLVerifier) HasBeenCalled(expectedCallsCount int) []*Invocation {
+	invocations := []*Invocation{}
+	for _, invocation := range verifier.api.receivedInvocations() {
+		graphQL, ok := parseGraphQLRequest(invocation.request, invocation.GetPayload())
+		if ok && graphQL.operation() == verifier.operationName {
+			invocations = append(invocations, invocation)
+		}
+	}
+	actualCallsCount := len(invocations)
+	if actualCallsCount != expectedCallsCount {
+		verifier.api.testState.Fatalf("got %d calls of GraphQL operation %s but was expecting %d\n",
+			actualCallsCount, verifier.operationName, expectedCallsCount)
+	}
+	for _, invocation := range invocations {
+		invocation.markVerified()
+	}
+	return invocations
+}
+
+// HasBeenCalledOnce asserts that the GraphQL operation has been called exactly once then returns the invocation.
+func (verifier *GraphQLVerifier) HasBeenCalledOnce() *Invocation {
+	invocations := verifier.HasBeenCalled(1)
+	if len(invocations) > 0 {
+		return invocations[0]
+	}
+	return nil
+}
+
+// HasNotBeenCalled asserts that the GraphQL operation has not been called.
+func (verifier *GraphQLVerifier) HasNotBeenCalled() {
+	_ = verifier.HasBeenCalled(0)
+}
+
+// GetGraphQLVariables returns the variables of a GraphQL invocation, nil when the request is not a GraphQL one.
+func (call *Invocation) GetGraphQLVariables() map[string]any {
+	graphQL, ok := parseGraphQLRequest(call.request, call.GetPayload())
+	if !ok {
+		return nil
+	}
+	return graphQL.Variables
+}
+
+// WithVariables asserts that the variables of a GraphQL invocation (see APIMock.VerifyGraphQL) equal the expected
+// ones, the comparison being done on their JSON representation.
+func (call *Invocation) WithVariables(expected map[string]any) *Invocation {
+	untypedExpected, err := normalizeJSON(expected)
+	if err != nil {
+		call.testState.Fatal(err)
+	}
+	actual := call.GetGraphQLVariables()
+	if actual == nil {
+		actual = map[string]any{}
+	}
+	if untypedExpected == nil {
+		untypedExpected = map[string]any{}
+	}
+	call.assertEqual(untypedExpected, actual, func(value any) any {
+		data, _ := json.Marshal(value)
+		return string(call.redaction.body(data))
+	})
+	return call
+}
+
+// parseGraphQLRequest returns the GraphQL request read from the JSON body of a POST request or from the query
+// parameters of a GET request, and whether the request is a GraphQL one.
+func parseGraphQLRequest(request *http.Request, payload []byte) (*graphQLRequest, bool) {
+	graphQL := &graphQLRequest{}
+	if request.Method == http.MethodGet {
+		query := request.URL.Query()
+		graphQL.Query = query.Get("query")
+		graphQL.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &graphQL.Variables); err != nil {
+				return nil, false
+			}
+		}
+	} else if mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type")); mediaType == "application/graphql" {
+		graphQL.Query = string(payload)
+		graphQL.OperationName = request.URL.Query().Get("operationName")
+	} else if err := json.Unmarshal(payload, graphQL); err != nil {
+		return nil, false
+	}
+	return graphQL, graphQL.Query != ""
+}
+
+// operation returns the name of the requested operation, read from the first operation definition of the query
+// document when not given (the fragment definitions being skipped), empty for an anonymous operation.
+func (graphQL *graphQLRequest) operation() string {
+	if graphQL.OperationName != "" {
+		return graphQL.OperationName
+	}
+	tokens := []string{}
+	for _, token := range graphQLTokenPattern.FindAllString(graphQL.Query, -1) {
+		if !strings.HasPrefix(token, "#") {
+			tokens = append(tokens, token)
+		}
+	}
+	depth := 0
+	previous := ""
+	for i, token := range tokens {
+		switch token {
+		case "{":
+			if depth == 0 && (previous == "" || previous == "}") {
+				// shorthand anonymous query
+				return ""
+			}
+			depth++
+		case "}":
+			depth--
+		case "query", "mutation", "subscription":
+			if depth == 0 && previous != "on" && previous != "fragment" {
+				if i+1 < len(tokens) && graphQLNamePattern.MatchString(tokens[i+1]) {
+					return tokens[i+1]
+				}
+				// anonymous operation
+				return ""
+			}
+		}
+		if depth == 0 {
+			previous = token
+		}
+	}
+	return ""
+}
+
+// containsVariables returns whether the actual variables contain the expected ones.
+func containsVariables(actual map[string]any, expected map[string]any) bool {
+	for name, value := range expected {
+		actualValue, ok := actual[name]
+		if !ok || !reflect.DeepEqual(value, actualValue) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,230 @@
+package mockhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StubGraphQL(t *testing.T) {
+	t.Parallel()
+
+	postGraphQL := func(t *testing.T, mockedAPI *APIMock, request map[string]any) string {
+		t.Helper()
+		body, err := json.Marshal(request)
+		require.NoError(t, err)
+		response, err := http.Post(mockedAPI.GetURL().String()+"/graphql", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		responseBody, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return string(responseBody)
+	}
+
+	t.Run("should return the response of the requested operation", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.StubGraphQL("/graphql").
+			Operation("GetUser").WithData(map[string]any{"user": map[string]any{"name": "John"}}).
+			Operation("DeleteUser").WithData(map[string]any{"deleteUser": true})
+
+		// Act
+		getUser := postGraphQL(t, mockedAPI, map[string]any{
+			"query":         "query GetUser($id: ID!) { user(id: $id) { name } }",
+			"operationName": "GetUser",
+			"variables":     map[string]any{"id": "42"},
+		})
+		deleteUser := postGraphQL(t, mockedAPI, map[string]any{
+			"query": "# deletes the user\nmutation DeleteUser { deleteUser(id: 42) }",
+		})
+
+		// Assert
+		assert := assertions.New(t)
+		assert.JSONEq(`{"data": {"user": {"name": "John"}}}`, getUser)
+		assert.JSONEq(`{"data": {"deleteUser": true}}`, deleteUser)
+		mockedAPI.VerifyGraphQL("GetUser").HasBeenCalledOnce().WithVariables(map[string]any{"id": "42"})
+		mockedAPI.VerifyGraphQL("DeleteUser").HasBeenCalledOnce().WithVariables(nil)
+		mockedAPI.VerifyGraphQL("UpdateUser").HasNotBeenCalled()
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should select the stub matching the variables", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.StubGraphQL("/graphql").
+			Operation("GetUser").WithVariables(map[string]any{"id": 42}).
+			WithData(map[string]any{"user": map[string]any{"name": "John"}}).
+			Operation("GetUser").WithVariables(map[string]any{"id": 43}).
+			WithErrors(GraphQLError{Message: "user not found", Path: []any{"user"}})
+
+		// Act
+		response := postGraphQL(t, mockedAPI, map[string]any{
+			"query":     "query GetUser($id: Int!, $verbose: Boolean) { user(id: $id) { name } }",
+			"variables": map[string]any{"id": 43, "verbose": true},
+		})
+
+		// Assert
+		assertions.JSONEq(t, `{"data": null, "errors": [{"message": "user not found", "path": ["user"]}]}`, response)
+		mockedAPI.VerifyGraphQL("GetUser").HasBeenCalledOnce().WithVariables(map[string]any{"id": 43, "verbose": true})
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should answer GET requests", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.StubGraphQL("/graphql").
+			Operation("GetUser").WithVariables(map[string]any{"id": "42"}).
+			WithData(map[string]any{"user": map[string]any{"name": "John"}})
+		query := url.Values{
+			"query":     {"query GetUser($id: ID!) { user(id: $id) { name } }"},
+			"variables": {`{"id": "42"}`},
+		}
+
+		// Act
+		response, err := http.Get(mockedAPI.GetURL().String() + "/graphql?" + query.Encode())
+
+		// Assert
+		require.NoError(t, err)
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		assertions.JSONEq(t, `{"data": {"user": {"name": "John"}}}`, string(body))
+		mockedAPI.VerifyGraphQL("GetUser").HasBeenCalledOnce().WithVariables(map[string]any{"id": "42"})
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should answer application/graphql requests", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.StubGraphQL("/graphql").Operation("ListUsers").WithResponse(http.StatusOK, map[string]any{"data": []any{}})
+
+		// Act
+		response, err := http.Post(mockedAPI.GetURL().String()+"/graphql", "application/graphql",
+			strings.NewReader("query ListUsers { users { name } }"))
+
+		// Assert
+		require.NoError(t, err)
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		assertions.JSONEq(t, `{"data": []}`, string(body))
+		mockedAPI.VerifyGraphQL("ListUsers").HasBeenCalledOnce()
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should read the name of the first operation following the fragments", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.StubGraphQL("/graphql").
+			Operation("GetUser").WithData(map[string]any{"user": map[string]any{"name": "John"}}).
+			Operation("").WithData(map[string]any{"users": []any{}})
+
+		// Act
+		getUser := postGraphQL(t, mockedAPI, map[string]any{
+			"query": "fragment UserFields on User { name }\n" +
+				"query GetUser($id: ID!) { user(id: $id) { ...UserFields } }",
+		})
+		anonymous := postGraphQL(t, mockedAPI, map[string]any{
+			"query": "fragment query on User { name } query ($first: Int) { users(first: $first) { ...query } }",
+		})
+		commented := postGraphQL(t, mockedAPI, map[string]any{
+			"query": `fragment F on User { avatar(tag: "#1") description(format: """# {markdown}""") } ` +
+				`query GetUser # the user query
+				{ user(id: 42) { ...F } }`,
+		})
+
+		// Assert
+		assert := assertions.New(t)
+		assert.JSONEq(`{"data": {"user": {"name": "John"}}}`, getUser)
+		assert.JSONEq(`{"data": {"users": []}}`, anonymous)
+		assert.JSONEq(`{"data": {"user": {"name": "John"}}}`, commented)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should not answer the requests of other methods", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.StubGraphQL("/graphql").Operation("ListUsers").WithData([]any{})
+		request, err := http.NewRequest(http.MethodPut, mockedAPI.GetURL().String()+"/graphql",
+			strings.NewReader(`{"query": "query ListUsers { users { name } }"}`))
+		require.NoError(t, err)
+
+		// Act
+		_, err = http.DefaultClient.Do(request)
+
+		// Assert
+		require.NoError(t, err)
+		testState.AssertFailedWithFatal()
+	})
+
+	t.Run("should fail on unmocked operation", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.StubGraphQL("/graphql").Operation("GetUser").WithData(nil)
+
+		// Act
+		postGraphQL(t, mockedAPI, map[string]any{"query": "query ListUsers { users { name } }"})
+
+		// Assert
+		testState.AssertFailedWithFatal()
+	})
+
+	t.Run("WithVariables() should fail when the variables are not the expected ones", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.StubGraphQL("/graphql").Operation("GetUser").WithData(nil)
+		postGraphQL(t, mockedAPI, map[string]any{
+			"query":     "query GetUser($id: ID!) { user(id: $id) { name } }",
+			"variables": map[string]any{"id": "42"},
+		})
+
+		// Act
+		mockedAPI.VerifyGraphQL("GetUser").HasBeenCalledOnce().WithVariables(map[string]any{"id": "43"})
+
+		// Assert
+		testState.AssertFailedWithError()
+	})
+
+	t.Run("VerifyGraphQL() should fail when the operation has not been called", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		mockedAPI.VerifyGraphQL("GetUser").HasBeenCalledOnce()
+
+		// Assert
+		testState.AssertFailedWithFatal()
+	})
+}
@@ -0,0 +1,215 @@
+package mockhttp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// GRPCStubBuilder is a helper to build stubs for a unary gRPC method, see APIMock.StubGRPC.
+type GRPCStubBuilder struct {
+	stub   *StubBuilder
+	method protoreflect.MethodDescriptor
+}
+
+// GRPCRequestMatcher reports whether a gRPC stub handles the request message.
+type GRPCRequestMatcher func(request proto.Message) bool
+
+// StubGRPC creates a new stub for the unary gRPC method of the given full name (e.g. "/acme.users.v1.Users/GetUser"),
+// whose service must be registered in the global protobuf registry, as done by the generated code. The stub serves
+// gRPC clients, over HTTP/2 (see WithHTTP2) as well as gRPC-Web clients, the requests being recorded as POST
+// invocations of the method path (see VerifyGRPC).
+func (mockedAPI *APIMock) StubGRPC(fullMethod string) *GRPCStubBuilder {
+	method, err := findGRPCMethod(fullMethod)
+	if err != nil {
+		mockedAPI.testState.Fatal(err)
+	}
+	return &GRPCStubBuilder{
+		stub:   mockedAPI.Stub(http.MethodPost, grpcPath(fullMethod)),
+		method: method,
+	}
+}
+
+// VerifyGRPC creates a new CallVerifier instance for the gRPC method of the given full name, the request messages of
+// the invocations being available through Invocation.ReadGRPCRequest.
+func (mockedAPI *APIMock) VerifyGRPC(fullMethod string) *CallVerifier {
+	return mockedAPI.Verify(http.MethodPost, grpcPath(fullMethod))
+}
+
+// Matching restricts the stub to the request messages accepted by the matcher.
+func (stub *GRPCStubBuilder) Matching(matcher GRPCRequestMatcher) *GRPCStubBuilder {
+	stub.stub.Matching(func(request *http.Request, payload []byte) bool {
+		message, err := decodeGRPCRequest(stub.method, request, payload)
+		return err == nil && matcher(message)
+	})
+	return stub
+}
+
+// WithResponse creates a new stub handler returning the given response message with the OK status.
+func (stub *GRPCStubBuilder) WithResponse(response proto.Message) *APIMock {
+	data, err := proto.Marshal(response)
+	if err != nil {
+		stub.stub.api.testState.Fatal(err)
+	}
+	return stub.stub.With(func(writer http.ResponseWriter, request *http.Request) {
+		writeGRPCResponse(writer, request, data, 0, "")
+	})
+}
+
+// WithStatus creates a new stub handler returning the given gRPC status code (e.g. 5 for NOT_FOUND) and message,
+// without response message.
+func (stub *GRPCStubBuilder) WithStatus(code int, message string) *APIMock {
+	return stub.stub.With(func(writer http.ResponseWriter, request *http.Request) {
+		writeGRPCResponse(writer, request, nil, code, message)
+	})
+}
+
+// ReadGRPCRequest decodes the request message of a gRPC invocation (see APIMock.VerifyGRPC) into the given message.
+func (call *Invocation) ReadGRPCRequest(message proto.Message) {
+	data, err := grpcMessageData(call.request, call.GetPayload())
+	if err == nil {
+		err = proto.Unmarshal(data, message)
+	}
+	if err != nil {
+		call.testState.Fatal(err)
+	}
+}
+
+// WithGRPCRequest asserts that the request message of a gRPC invocation equals the expected message.
+func (call *Invocation) WithGRPCRequest(expected proto.Message) *Invocation {
+	actual := expected.ProtoReflect().New().Interface()
+	call.ReadGRPCRequest(actual)
+	if !proto.Equal(expected, actual) {
+		call.testState.Errorf("gRPC request %v found where %v was expected", actual, expected)
+	}
+	return call
+}
+
+// grpcPath returns the path of the gRPC method of the given full name, with or without its leading slash.
+func grpcPath(fullMethod string) string {
+	return "/" + strings.TrimPrefix(fullMethod, "/")
+}
+
+func findGRPCMethod(fullMethod string) (protoreflect.MethodDescriptor, error) {
+	serviceName, methodName, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid gRPC method %s, expecting /package.Service/Method", fullMethod)
+	}
+	descriptor, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("gRPC service %s not found: %w", serviceName, err)
+	}
+	service, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a gRPC service", serviceName)
+	}
+	method := service.Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		return nil, fmt.Errorf("gRPC method %s not found in service %s", methodName, serviceName)
+	}
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return nil, fmt.Errorf("gRPC method %s is a streaming method, only unary methods are supported", fullMethod)
+	}
+	return method, nil
+}
+
+// decodeGRPCRequest returns the request message of the gRPC request, whose type is found in the global registry.
+func decodeGRPCRequest(method protoreflect.MethodDescriptor, request *http.Request, payload []byte) (proto.Message, error) {
+	if method == nil {
+		return nil, errors.New("unknown gRPC method")
+	}
+	data, err := grpcMessageData(request, payload)
+	if err != nil {
+		return nil, err
+	}
+	var message proto.Message
+	if messageType, err := protoregistry.GlobalTypes.FindMessageByName(method.Input().FullName()); err == nil {
+		message = messageType.New().Interface()
+	} else {
+		message = dynamicpb.NewMessage(method.Input())
+	}
+	return message, proto.Unmarshal(data, message)
+}
+
+// isGRPCWeb returns whether the request content type is a gRPC-Web one, and whether it is the base64 text variant.
+func isGRPCWeb(request *http.Request) (bool, bool) {
+	contentType := request.Header.Get("Content-Type")
+	return strings.HasPrefix(contentType, "application/grpc-web"), strings.HasPrefix(contentType, "application/grpc-web-text")
+}
+
+// grpcMessageData returns the message of the length-prefixed frame of a gRPC or gRPC-Web request body.
+func grpcMessageData(request *http.Request, payload []byte) ([]byte, error) {
+	if _, text := isGRPCWeb(request); text {
+		decoded, err := base64.StdEncoding.DecodeString(string(payload))
+		if err != nil {
+			return nil, err
+		}
+		payload = decoded
+	}
+	if len(payload) < 5 {
+		return nil, errors.New("invalid gRPC message frame")
+	}
+	if payload[0] != 0 {
+		return nil, errors.New("compressed gRPC messages are not supported")
+	}
+	length := binary.BigEndian.Uint32(payload[1:5])
+	if uint32(len(payload)-5) < length {
+		return nil, errors.New("truncated gRPC message frame")
+	}
+	return payload[5 : 5+length], nil
+}
+
+func grpcFrame(flags byte, data []byte) []byte {
+	frame := make([]byte, 5, 5+len(data))
+	frame[0] = flags
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	return append(frame, data...)
+}
+
+// writeGRPCResponse writes the response message, if any, then the status, as trailers for gRPC clients or as a
+// trailers frame for gRPC-Web clients.
+func writeGRPCResponse(writer http.ResponseWriter, request *http.Request, data []byte, code int, message string) {
+	web, text := isGRPCWeb(request)
+	if !web {
+		writer.Header().Set("Content-Type", "application/grpc")
+		writer.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+		writer.Header().Set(http.TrailerPrefix+"Grpc-Message", grpcEncodeMessage(message))
+		writer.WriteHeader(http.StatusOK)
+		if data != nil {
+			_, _ = writer.Write(grpcFrame(0, data))
+		}
+		return
+	}
+
+	body := &bytes.Buffer{}
+	if data != nil {
+		body.Write(grpcFrame(0, data))
+	}
+	trailers := fmt.Sprintf("grpc-status: %d\r\ngrpc-message: %s\r\n", code, grpcEncodeMessage(message))
+	body.Write(grpcFrame(0x80, []byte(trailers)))
+	if text {
+		writer.Header().Set("Content-Type", "application/grpc-web-text")
+		writer.WriteHeader(http.StatusOK)
+		_, _ = writer.Write([]byte(base64.StdEncoding.EncodeToString(body.Bytes())))
+		return
+	}
+	writer.Header().Set("Content-Type", "application/grpc-web+proto")
+	writer.WriteHeader(http.StatusOK)
+	_, _ = writer.Write(body.Bytes())
+}
+
+// grpcEncodeMessage percent-encodes the status message as required by the gRPC protocol.
+func grpcEncodeMessage(message string) string {
+	return url.PathEscape(message)
+}
@@ -0,0 +1,238 @@
+package mockhttp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const greetMethod = "/mockhttp.test.Greeter/Greet"
+
+var greetingDescriptor = registerGreeterService()
+
+// registerGreeterService registers a test service in the global registry, as the generated code would do.
+func registerGreeterService() protoreflect.MessageDescriptor {
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("mockhttp/test/greeter.proto"),
+		Package: proto.String("mockhttp.test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Greeting"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:     proto.String("text"),
+				JsonName: proto.String("text"),
+				Number:   proto.Int32(1),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			}},
+		}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Greeter"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("Greet"),
+				InputType:  proto.String(".mockhttp.test.Greeting"),
+				OutputType: proto.String(".mockhttp.test.Greeting"),
+			}, {
+				Name:            proto.String("Watch"),
+				InputType:       proto.String(".mockhttp.test.Greeting"),
+				OutputType:      proto.String(".mockhttp.test.Greeting"),
+				ServerStreaming: proto.Bool(true),
+			}},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		panic(err)
+	}
+	if err := protoregistry.GlobalFiles.RegisterFile(file); err != nil {
+		panic(err)
+	}
+	return file.Messages().ByName("Greeting")
+}
+
+func greeting(text string) proto.Message {
+	message := dynamicpb.NewMessage(greetingDescriptor)
+	message.Set(greetingDescriptor.Fields().ByName("text"), protoreflect.ValueOfString(text))
+	return message
+}
+
+func greetingText(message proto.Message) string {
+	return message.ProtoReflect().Get(greetingDescriptor.Fields().ByName("text")).String()
+}
+
+func grpcRequestBody(t *testing.T, message proto.Message) []byte {
+	t.Helper()
+	data, err := proto.Marshal(message)
+	require.NoError(t, err)
+	return grpcFrame(0, data)
+}
+
+func Test_StubGRPC(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should answer gRPC requests over HTTP/2", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithHTTP2())
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.StubGRPC(greetMethod).WithResponse(greeting("hello John"))
+		request, err := http.NewRequest(http.MethodPost, mockedAPI.GetURL().String()+greetMethod,
+			bytes.NewReader(grpcRequestBody(t, greeting("John"))))
+		require.NoError(t, err)
+		request.Header.Set("Content-Type", "application/grpc")
+		request.Header.Set("TE", "trailers")
+
+		// Act
+		response, err := mockedAPI.HTTP2Client().Do(request)
+
+		// Assert
+		require.NoError(t, err)
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		assert := assertions.New(t)
+		assert.Equal(http.StatusOK, response.StatusCode)
+		assert.Equal("application/grpc", response.Header.Get("Content-Type"))
+		assert.Equal("0", response.Trailer.Get("Grpc-Status"))
+		actual := dynamicpb.NewMessage(greetingDescriptor)
+		require.NoError(t, proto.Unmarshal(body[5:], actual))
+		assert.Equal("hello John", greetingText(actual))
+		mockedAPI.VerifyGRPC(greetMethod).HasBeenCalledOnce().WithGRPCRequest(greeting("John"))
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should answer gRPC-Web requests", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.StubGRPC(greetMethod).WithResponse(greeting("hello John"))
+
+		// Act
+		response, err := http.Post(mockedAPI.GetURL().String()+greetMethod, "application/grpc-web+proto",
+			bytes.NewReader(grpcRequestBody(t, greeting("John"))))
+
+		// Assert
+		require.NoError(t, err)
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		expected, err := proto.Marshal(greeting("hello John"))
+		require.NoError(t, err)
+		expected = append(grpcFrame(0, expected), grpcFrame(0x80, []byte("grpc-status: 0\r\ngrpc-message: \r\n"))...)
+		assert := assertions.New(t)
+		assert.Equal("application/grpc-web+proto", response.Header.Get("Content-Type"))
+		assert.Equal(expected, body)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should answer gRPC-Web text requests", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.StubGRPC(greetMethod).WithStatus(5, "greeting not found")
+		requestBody := base64.StdEncoding.EncodeToString(grpcRequestBody(t, greeting("John")))
+
+		// Act
+		response, err := http.Post(mockedAPI.GetURL().String()+greetMethod, "application/grpc-web-text",
+			bytes.NewReader([]byte(requestBody)))
+
+		// Assert
+		require.NoError(t, err)
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		decoded, err := base64.StdEncoding.DecodeString(string(body))
+		require.NoError(t, err)
+		assert := assertions.New(t)
+		assert.Equal("application/grpc-web-text", response.Header.Get("Content-Type"))
+		assert.Equal(grpcFrame(0x80, []byte("grpc-status: 5\r\ngrpc-message: greeting%20not%20found\r\n")), decoded)
+		mockedAPI.VerifyGRPC(greetMethod).HasBeenCalledOnce().WithGRPCRequest(greeting("John"))
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should select the stub matching the request message", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.StubGRPC(greetMethod).
+			Matching(func(request proto.Message) bool { return greetingText(request) == "Jane" }).
+			WithStatus(7, "forbidden")
+		mockedAPI.StubGRPC(greetMethod).
+			Matching(func(request proto.Message) bool { return greetingText(request) == "John" }).
+			WithResponse(greeting("hello John"))
+
+		// Act
+		response, err := http.Post(mockedAPI.GetURL().String()+greetMethod, "application/grpc-web+proto",
+			bytes.NewReader(grpcRequestBody(t, greeting("John"))))
+
+		// Assert
+		require.NoError(t, err)
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		assertions.Contains(t, string(body), "grpc-status: 0\r\n")
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should fail when the request message is not the expected one", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.StubGRPC(greetMethod).WithResponse(greeting("hello"))
+		response, err := http.Post(mockedAPI.GetURL().String()+greetMethod, "application/grpc-web+proto",
+			bytes.NewReader(grpcRequestBody(t, greeting("John"))))
+		require.NoError(t, err)
+		_, err = io.ReadAll(response.Body)
+		require.NoError(t, err)
+
+		// Act
+		mockedAPI.VerifyGRPC(greetMethod).HasBeenCalledOnce().WithGRPCRequest(greeting("Jane"))
+
+		// Assert
+		testState.AssertFailedWithError()
+	})
+
+	t.Run("should fail on unknown method", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		mockedAPI.StubGRPC("/mockhttp.test.Greeter/Unknown")
+
+		// Assert
+		testState.AssertFailedWithFatal()
+	})
+
+	t.Run("should fail on streaming method", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		mockedAPI.StubGRPC("/mockhttp.test.Greeter/Watch")
+
+		// Assert
+		testState.AssertFailedWithFatal()
+	})
+}
@@ -0,0 +1,233 @@
+package mockhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// ExportHAR writes all the recorded invocations, along with the responses served, as an HTTP Archive (HAR 1.2).
+// Sensitive data is redacted according to the configured redaction.
+func (mockedAPI *APIMock) ExportHAR(writer io.Writer) error {
+	mockedAPI.mu.Lock()
+	journal := append([]*Invocation{}, mockedAPI.journal...)
+	mockedAPI.mu.Unlock()
+
+	archive := harArchive{
+		Log: harLog{
+			Version: "1.2",
+			Creator: harCreator{Name: "gomockhttp", Version: "1"},
+			Entries: []*harEntry{},
+		},
+	}
+	for _, invocation := range journal {
+		archive.Log.Entries = append(archive.Log.Entries, newHAREntry(mockedAPI.GetURL(), invocation, mockedAPI.redaction))
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(archive)
+}
+
+// FromHAR creates a new APIMock instance stubbing the entries of the given HTTP Archive, as captured by a browser.
+// Entries are replayed the same way as the interactions of a cassette, see Replay.
+func FromHAR(testState T, reader io.Reader, options ...Option) *APIMock {
+	mockedAPI := API(testState, options...)
+	archive := harArchive{}
+	if err := json.NewDecoder(reader).Decode(&archive); err != nil {
+		testState.Fatal(fmt.Errorf("invalid HAR: %w", err))
+		return mockedAPI
+	}
+
+	interactions := make([]*cassetteInteraction, 0, len(archive.Log.Entries))
+	for _, entry := range archive.Log.Entries {
+		interaction, err := entry.interaction()
+		if err != nil {
+			testState.Fatal(err)
+			return mockedAPI
+		}
+		interactions = append(interactions, interaction)
+	}
+	newCassetteReplayer(mockedAPI, interactions)
+	return mockedAPI
+}
+
+type harArchive struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string      `json:"version"`
+	Creator harCreator  `json:"creator"`
+	Entries []*harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	// Encoding is "base64" when the text is the base64 encoding of a binary body, as for the response content.
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// newHAREntry returns the HAR entry of the invocation, with its sensitive data redacted.
+func newHAREntry(serverURL *url.URL, invocation *Invocation, redaction *Redaction) *harEntry {
+	interaction := newCassetteInteraction(invocation, redaction)
+
+	requestURL := *serverURL
+	requestURL.Path = interaction.Request.Path
+	requestURL.RawQuery = interaction.Request.Query
+	query, _ := url.ParseQuery(interaction.Request.Query)
+	protocol := invocation.GetRequest().Proto
+
+	entry := &harEntry{
+		StartedDateTime: invocation.receivedAt.UTC().Format(time.RFC3339Nano),
+		Request: harRequest{
+			Method:      interaction.Request.Method,
+			URL:         requestURL.String(),
+			HTTPVersion: protocol,
+			Cookies:     []harNameValue{},
+			Headers:     harNameValues(interaction.Request.Headers),
+			QueryString: harNameValues(query),
+			HeadersSize: -1,
+			BodySize:    len(invocation.GetPayload()),
+		},
+		Response: harResponse{
+			Status:      interaction.Response.Status,
+			StatusText:  http.StatusText(interaction.Response.Status),
+			HTTPVersion: protocol,
+			Cookies:     []harNameValue{},
+			Headers:     harNameValues(interaction.Response.Headers),
+			Content: harContent{
+				Size:     len(invocation.GetResponsePayload()),
+				MimeType: interaction.Response.Headers.Get("Content-Type"),
+				Text:     interaction.Response.Body,
+				Encoding: interaction.Response.BodyEncoding,
+			},
+			HeadersSize: -1,
+			BodySize:    len(invocation.GetResponsePayload()),
+		},
+	}
+	if len(invocation.GetPayload()) > 0 {
+		entry.Request.PostData = &harPostData{
+			MimeType: interaction.Request.Headers.Get("Content-Type"),
+			Text:     interaction.Request.Body,
+			Encoding: interaction.Request.BodyEncoding,
+		}
+	}
+	return entry
+}
+
+func harNameValues(values map[string][]string) []harNameValue {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	nameValues := []harNameValue{}
+	for _, name := range names {
+		for _, value := range values[name] {
+			nameValues = append(nameValues, harNameValue{Name: name, Value: value})
+		}
+	}
+	return nameValues
+}
+
+// interaction converts the HAR entry to a replayable interaction.
+func (entry *harEntry) interaction() (*cassetteInteraction, error) {
+	requestURL, err := url.Parse(entry.Request.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HAR entry URL %s: %w", entry.Request.URL, err)
+	}
+	interaction := &cassetteInteraction{
+		Request: cassetteRequest{
+			Method:  entry.Request.Method,
+			Path:    requestURL.Path,
+			Query:   requestURL.RawQuery,
+			Headers: http.Header{},
+		},
+		Response: cassetteResponse{
+			Status:       entry.Response.Status,
+			Headers:      http.Header{},
+			Body:         entry.Response.Content.Text,
+			BodyEncoding: entry.Response.Content.Encoding,
+		},
+	}
+	if entry.Request.PostData != nil {
+		interaction.Request.Body = entry.Request.PostData.Text
+		interaction.Request.BodyEncoding = entry.Request.PostData.Encoding
+	}
+	for _, header := range entry.Request.Headers {
+		interaction.Request.Headers.Add(header.Name, header.Value)
+	}
+	for _, header := range entry.Response.Headers {
+		// the captured content is already decoded and its length may differ from the one sent over the network
+		if http.CanonicalHeaderKey(header.Name) == "Content-Encoding" || http.CanonicalHeaderKey(header.Name) == "Content-Length" {
+			continue
+		}
+		interaction.Response.Headers.Add(header.Name, header.Value)
+	}
+	if interaction.Response.Headers.Get("Content-Type") == "" && entry.Response.Content.MimeType != "" {
+		interaction.Response.Headers.Set("Content-Type", entry.Response.Content.MimeType)
+	}
+	return interaction, nil
+}
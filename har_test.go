@@ -0,0 +1,152 @@
+package mockhttp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const browserHAR = `{
+  "log": {
+    "version": "1.2",
+    "creator": {"name": "Firefox", "version": "128.0"},
+    "entries": [
+      {
+        "startedDateTime": "2025-01-01T10:00:00.000Z",
+        "request": {
+          "method": "GET",
+          "url": "https://api.example.com/users/42?expand=orders",
+          "httpVersion": "HTTP/2",
+          "headers": [{"name": "Accept", "value": "application/json"}],
+          "queryString": [{"name": "expand", "value": "orders"}]
+        },
+        "response": {
+          "status": 500,
+          "statusText": "Internal Server Error",
+          "httpVersion": "HTTP/2",
+          "headers": [
+            {"name": "content-type", "value": "application/json"},
+            {"name": "content-encoding", "value": "br"}
+          ],
+          "content": {"size": 27, "mimeType": "application/json", "text": "{\"error\": \"orders timeout\"}"}
+        }
+      }
+    ]
+  }
+}`
+
+func Test_HAR(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ExportHAR() should write the invocations and their responses", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithRedaction(Redaction{Headers: []string{"Authorization"}}))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodPost, "/users").WithJSON(http.StatusCreated, map[string]any{"id": 1})
+
+		request, err := http.NewRequest(http.MethodPost, mockedAPI.GetURL().String()+"/users?notify=true", bytes.NewBufferString(`{"name": "John"}`))
+		require.NoError(t, err)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Authorization", "Bearer secret")
+		_, err = http.DefaultClient.Do(request)
+		require.NoError(t, err)
+
+		// Act
+		output := &bytes.Buffer{}
+		err = mockedAPI.ExportHAR(output)
+
+		// Assert
+		require.NoError(t, err)
+		testState.AssertDidNotFailed()
+		archive := harArchive{}
+		require.NoError(t, json.Unmarshal(output.Bytes(), &archive))
+		require.Len(t, archive.Log.Entries, 1)
+
+		assert := assertions.New(t)
+		entry := archive.Log.Entries[0]
+		assert.Equal("1.2", archive.Log.Version)
+		assert.Equal(http.MethodPost, entry.Request.Method)
+		assert.Equal(mockedAPI.GetURL().String()+"/users?notify=true", entry.Request.URL)
+		assert.Contains(entry.Request.Headers, harNameValue{Name: "Authorization", Value: "Bearer [REDACTED]"})
+		assert.Equal([]harNameValue{{Name: "notify", Value: "true"}}, entry.Request.QueryString)
+		require.NotNil(t, entry.Request.PostData)
+		assert.Equal(`{"name": "John"}`, entry.Request.PostData.Text)
+		assert.Equal(http.StatusCreated, entry.Response.Status)
+		assert.Equal("application/json", entry.Response.Content.MimeType)
+		assert.JSONEq(`{"id": 1}`, entry.Response.Content.Text)
+	})
+
+	t.Run("ExportHAR() should base64 encode the binary request bodies", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodPost, "/upload").WithStatusCode(http.StatusNoContent)
+		binary := []byte{0xff, 0xfe, 0x00, 0x01}
+		_, err := http.Post(mockedAPI.GetURL().String()+"/upload", "application/octet-stream", bytes.NewReader(binary))
+		require.NoError(t, err)
+
+		// Act
+		output := &bytes.Buffer{}
+		err = mockedAPI.ExportHAR(output)
+
+		// Assert
+		require.NoError(t, err)
+		archive := harArchive{}
+		require.NoError(t, json.Unmarshal(output.Bytes(), &archive))
+		require.Len(t, archive.Log.Entries, 1)
+		postData := archive.Log.Entries[0].Request.PostData
+		require.NotNil(t, postData)
+		assert := assertions.New(t)
+		assert.Equal("base64", postData.Encoding)
+		assert.Equal(base64.StdEncoding.EncodeToString(binary), postData.Text)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("FromHAR() should stub the archive entries", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := FromHAR(testState, strings.NewReader(browserHAR))
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		response, err := http.Get(mockedAPI.GetURL().String() + "/users/42?expand=orders")
+
+		// Assert
+		require.NoError(t, err)
+		testState.AssertDidNotFailed()
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		assert := assertions.New(t)
+		assert.Equal(http.StatusInternalServerError, response.StatusCode)
+		assert.Equal("application/json", response.Header.Get("Content-Type"))
+		assert.Empty(response.Header.Get("Content-Encoding"))
+		assert.JSONEq(`{"error": "orders timeout"}`, string(body))
+		mockedAPI.Verify(http.MethodGet, "/users/42").HasBeenCalledOnce()
+	})
+
+	t.Run("FromHAR() should fail with an invalid archive", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+
+		// Act
+		mockedAPI := FromHAR(testState, strings.NewReader("not a HAR"))
+		t.Cleanup(mockedAPI.Close)
+
+		// Assert
+		testState.AssertFailedWithFatal()
+	})
+}
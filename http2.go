@@ -0,0 +1,40 @@
+package mockhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// WithHTTP2 makes the server serve HTTP/2 along with HTTP/1.1: negotiated with ALPN for a TLS server (see
+// APIWithTLS), or as cleartext h2c otherwise, either with prior knowledge or through an HTTP/1.1 upgrade. The
+// protocol of the requests can then be asserted on the invocations, see Invocation.WithProtocol.
+func WithHTTP2() Option {
+	return func(mockedAPI *APIMock) {
+		mockedAPI.http2 = true
+	}
+}
+
+// h2cHandler returns the handler serving cleartext HTTP/2 as well as HTTP/1.1 with the given handler.
+func h2cHandler(handler http.Handler) http.Handler {
+	return h2c.NewHandler(handler, &http2.Server{})
+}
+
+// HTTP2Client returns an http.Client using HTTP/2 only: over TLS, trusting the certificate of the server, when the
+// server uses TLS, or as cleartext h2c with prior knowledge otherwise. The server must have been created with WithHTTP2.
+func (mockedAPI *APIMock) HTTP2Client() *http.Client {
+	transport := &http2.Transport{}
+	if mockedAPI.testServer != nil && mockedAPI.testServer.TLS != nil {
+		transport.TLSClientConfig = &tls.Config{RootCAs: mockedAPI.CertPool()}
+	} else {
+		transport.AllowHTTP = true
+		transport.DialTLSContext = func(ctx context.Context, network string, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		}
+	}
+	return &http.Client{Transport: transport}
+}
@@ -0,0 +1,112 @@
+package mockhttp
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithHTTP2(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should serve HTTP/2 over TLS", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := APIWithTLS(testState, WithHTTP2())
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/health").WithStatusCode(http.StatusNoContent)
+
+		// Act
+		response, err := mockedAPI.TLSClient().Get(mockedAPI.GetURL().String() + "/health")
+
+		// Assert
+		require.NoError(t, err)
+		assertions.Equal(t, "HTTP/2.0", response.Proto)
+		mockedAPI.Verify(http.MethodGet, "/health").HasBeenCalledOnce().WithProtocol("HTTP/2.0")
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should serve cleartext HTTP/2 with prior knowledge", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithHTTP2())
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/health").WithStatusCode(http.StatusNoContent)
+
+		// Act
+		response, err := mockedAPI.HTTP2Client().Get(mockedAPI.GetURL().String() + "/health")
+
+		// Assert
+		require.NoError(t, err)
+		assertions.Equal(t, "HTTP/2.0", response.Proto)
+		mockedAPI.Verify(http.MethodGet, "/health").HasBeenCalledOnce().WithProtocol("HTTP/2.0")
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should keep serving HTTP/1.1", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithHTTP2())
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/health").WithStatusCode(http.StatusNoContent)
+
+		// Act
+		response, err := http.Get(mockedAPI.GetURL().String() + "/health")
+
+		// Assert
+		require.NoError(t, err)
+		assertions.Equal(t, "HTTP/1.1", response.Proto)
+		mockedAPI.Verify(http.MethodGet, "/health").HasBeenCalledOnce().WithProtocol("HTTP/1.1")
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should multiplex concurrent requests", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := APIWithTLS(testState, WithHTTP2())
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/health").WithStatusCode(http.StatusNoContent)
+		client := mockedAPI.HTTP2Client()
+
+		// Act
+		wg := sync.WaitGroup{}
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				response, err := client.Get(mockedAPI.GetURL().String() + "/health")
+				if assertions.NoError(t, err) {
+					_ = response.Body.Close()
+				}
+			}()
+		}
+		wg.Wait()
+
+		// Assert
+		for _, invocation := range mockedAPI.Verify(http.MethodGet, "/health").HasBeenCalled(10) {
+			invocation.WithProtocol("HTTP/2.0")
+		}
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should fail without listener", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+
+		// Act
+		mockedAPI := API(testState, WithoutListener(), WithHTTP2())
+		t.Cleanup(mockedAPI.Close)
+
+		// Assert
+		testState.AssertFailedWithFatal()
+	})
+}
@@ -0,0 +1,64 @@
+package mockhttp
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// InterceptHosts returns an http.Client sending the requests made to the given hosts to the mocked API, whatever the
+// scheme and the port of their URL, the other requests being sent through http.DefaultTransport. A host can include a
+// port to only intercept this port, or start with "*." to intercept all its subdomains:
+//
+//	client := mockedAPI.InterceptHosts("api.partner.com", "*.cdn.partner.com")
+//
+// The intercepted requests are served in-memory (see APIMock.Transport), their Host header keeping the original host
+// (see Invocation.GetHost). It avoids making the base URL of a client injectable just to target the mocked API.
+func (mockedAPI *APIMock) InterceptHosts(hosts ...string) *http.Client {
+	return &http.Client{Transport: mockedAPI.InterceptingTransport(hosts...)}
+}
+
+// InterceptingTransport returns the http.RoundTripper of the clients returned by InterceptHosts.
+func (mockedAPI *APIMock) InterceptingTransport(hosts ...string) http.RoundTripper {
+	return &interceptingTransport{
+		hosts:     hosts,
+		intercept: mockedAPI.Transport(),
+	}
+}
+
+// interceptingTransport dispatches the requests made to its hosts to the mocked API, and the others to the network.
+type interceptingTransport struct {
+	hosts     []string
+	intercept http.RoundTripper
+}
+
+func (transport *interceptingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if transport.intercepts(request) {
+		return transport.intercept.RoundTrip(request)
+	}
+	return http.DefaultTransport.RoundTrip(request)
+}
+
+func (transport *interceptingTransport) intercepts(request *http.Request) bool {
+	for _, host := range transport.hosts {
+		if matchesHost(host, request.URL) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesHost returns whether the URL host matches the given host, which may include a port or a "*." wildcard.
+func matchesHost(host string, requestURL *url.URL) bool {
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		hostname = host
+	} else if port != requestURL.Port() {
+		return false
+	}
+	if suffix, ok := strings.CutPrefix(hostname, "*."); ok {
+		return strings.HasSuffix(strings.ToLower(requestURL.Hostname()), "."+strings.ToLower(suffix))
+	}
+	return strings.EqualFold(hostname, requestURL.Hostname())
+}
@@ -0,0 +1,91 @@
+package mockhttp
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_InterceptHosts(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should route the intercepted hosts to the mocked API", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithoutListener())
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/orders/{id}").WithJSON(http.StatusOK, map[string]any{"id": 1})
+		client := mockedAPI.InterceptHosts("api.partner.com")
+
+		// Act
+		response, err := client.Get("https://api.partner.com/orders/1")
+
+		// Assert
+		require.NoError(t, err)
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		assert := assertions.New(t)
+		assert.Equal(http.StatusOK, response.StatusCode)
+		assert.JSONEq(`{"id": 1}`, string(body))
+		invocation := mockedAPI.Verify(http.MethodGet, "/orders/1").HasBeenCalledOnce()
+		assert.Equal("api.partner.com", invocation.GetHost())
+		invocation.WithHost("api.partner.com")
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should leave the other hosts untouched", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		otherAPI := API(testState)
+		t.Cleanup(otherAPI.Close)
+		otherAPI.Stub(http.MethodGet, "/health").WithStatusCode(http.StatusNoContent)
+		client := mockedAPI.InterceptHosts("api.partner.com")
+
+		// Act
+		response, err := client.Get(otherAPI.GetURL().String() + "/health")
+
+		// Assert
+		require.NoError(t, err)
+		assertions.Equal(t, http.StatusNoContent, response.StatusCode)
+		otherAPI.Verify(http.MethodGet, "/health").HasBeenCalledOnce()
+		mockedAPI.Verify(http.MethodGet, "/health").HasNotBeenCalled()
+		testState.AssertDidNotFailed()
+	})
+}
+
+func Test_matchesHost(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		host     string
+		url      string
+		expected bool
+	}{
+		{"api.partner.com", "https://api.partner.com/orders", true},
+		{"api.partner.com", "http://API.Partner.com:8080/orders", true},
+		{"api.partner.com", "https://partner.com/orders", false},
+		{"api.partner.com", "https://api.partner.com.evil.com/orders", false},
+		{"api.partner.com:8443", "https://api.partner.com:8443/orders", true},
+		{"api.partner.com:8443", "https://api.partner.com/orders", false},
+		{"*.partner.com", "https://eu.api.partner.com/orders", true},
+		{"*.partner.com", "https://partner.com/orders", false},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.host+" "+testCase.url, func(t *testing.T) {
+			t.Parallel()
+			requestURL, err := url.Parse(testCase.url)
+			require.NoError(t, err)
+
+			assertions.Equal(t, testCase.expected, matchesHost(testCase.host, requestURL))
+		})
+	}
+}
@@ -2,20 +2,34 @@ package mockhttp
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"slices"
+	"sync"
+	"time"
 
 	assertions "github.com/stretchr/testify/assert"
 )
 
 // Invocation represents a single HTTP request made to the mock server.
 type Invocation struct {
-	testState T
-	request   *http.Request
-	payload   []byte
+	testState       T
+	request         *http.Request
+	payload         []byte
+	response        *http.Response
+	responsePayload []byte
+	redaction       *Redaction
+	receivedAt      time.Time
+	verified        bool
+	assertedHeaders []string
+	mu              sync.Mutex
 }
 
 func newInvocation(request *http.Request, testState T) *Invocation {
@@ -31,9 +45,10 @@ func newInvocation(request *http.Request, testState T) *Invocation {
 	}
 
 	return &Invocation{
-		request:   request,
-		payload:   data,
-		testState: testState,
+		request:    request,
+		payload:    data,
+		testState:  testState,
+		receivedAt: time.Now(),
 	}
 }
 
@@ -47,10 +62,75 @@ func (call *Invocation) GetPayload() []byte {
 	return call.payload
 }
 
+// GetHost returns the host the invocation request was sent to, as found in its Host header. For the requests sent
+// through a client returned by APIMock.InterceptHosts, it is the original host of the request URL.
+func (call *Invocation) GetHost() string {
+	return call.request.Host
+}
+
+// GetResponse returns the response served by the stub, nil if the invocation was not stubbed.
+// The response body has already been consumed, see GetResponsePayload.
+func (call *Invocation) GetResponse() *http.Response {
+	call.mu.Lock()
+	defer call.mu.Unlock()
+	return call.response
+}
+
+// GetResponsePayload returns the payload of the response served by the stub
+func (call *Invocation) GetResponsePayload() []byte {
+	call.mu.Lock()
+	defer call.mu.Unlock()
+	return call.responsePayload
+}
+
+// markVerified marks the invocation as returned by a CallVerifier.
+func (call *Invocation) markVerified() {
+	call.mu.Lock()
+	defer call.mu.Unlock()
+	call.verified = true
+}
+
+// isVerified reports whether the invocation has been returned by a CallVerifier.
+func (call *Invocation) isVerified() bool {
+	call.mu.Lock()
+	defer call.mu.Unlock()
+	return call.verified
+}
+
+// getAssertedHeaders returns the names of the headers asserted on the invocation request.
+func (call *Invocation) getAssertedHeaders() []string {
+	call.mu.Lock()
+	defer call.mu.Unlock()
+	return append([]string{}, call.assertedHeaders...)
+}
+
+func (call *Invocation) setResponse(recorder *responseRecorder) {
+	call.mu.Lock()
+	defer call.mu.Unlock()
+	statusCode := recorder.getStatusCode()
+	call.response = &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         call.request.Proto,
+		ProtoMajor:    call.request.ProtoMajor,
+		ProtoMinor:    call.request.ProtoMinor,
+		Header:        recorder.Header().Clone(),
+		Body:          http.NoBody,
+		ContentLength: int64(recorder.body.Len()),
+		Request:       call.request,
+	}
+	call.responsePayload = bytes.Clone(recorder.body.Bytes())
+}
+
 // WithHeader asserts that the invocation request contains the specified header
 func (call *Invocation) WithHeader(name string, expectedValues ...string) *Invocation {
+	call.mu.Lock()
+	call.assertedHeaders = append(call.assertedHeaders, name)
+	call.mu.Unlock()
 	values := call.request.Header.Values(name)
-	assertions.Equal(call.testState, expectedValues, values)
+	call.assertEqual(expectedValues, values, func(value any) any {
+		return call.redaction.headerValues(name, value.([]string))
+	})
 	return call
 }
 
@@ -77,15 +157,99 @@ func (call *Invocation) WithBearerAuthHeader(token string) *Invocation {
 	return call.WithAuthHeader("Bearer", token)
 }
 
+// WithHost asserts that the invocation request was sent to the specified host. The port is only compared when the
+// expected host specifies one.
+func (call *Invocation) WithHost(expected string) *Invocation {
+	actual := call.GetHost()
+	if _, _, err := net.SplitHostPort(expected); err != nil {
+		if hostname, _, err := net.SplitHostPort(actual); err == nil {
+			actual = hostname
+		}
+	}
+	assertions.Equal(call.testState, expected, actual)
+	return call
+}
+
+// GetProtocol returns the protocol of the invocation request (e.g. "HTTP/1.1" or "HTTP/2.0").
+func (call *Invocation) GetProtocol() string {
+	return call.request.Proto
+}
+
+// WithProtocol asserts that the invocation request was made with the specified protocol (e.g. "HTTP/2.0").
+func (call *Invocation) WithProtocol(expected string) *Invocation {
+	assertions.Equal(call.testState, expected, call.GetProtocol())
+	return call
+}
+
+// GetClientCertificate returns the certificate presented by the client, nil if the invocation request was not made
+// over TLS with a client certificate (see WithClientCertificates).
+func (call *Invocation) GetClientCertificate() *x509.Certificate {
+	if call.request.TLS == nil || len(call.request.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return call.request.TLS.PeerCertificates[0]
+}
+
+// WithClientCertificateSubject asserts that the client presented a certificate with the specified subject, given
+// either as a distinguished name (e.g. "CN=payments,O=Acme") or as a common name.
+func (call *Invocation) WithClientCertificateSubject(expected string) *Invocation {
+	certificate := call.GetClientCertificate()
+	if certificate == nil {
+		call.testState.Errorf("no client certificate found where one with subject '%s' was expected", expected)
+		return call
+	}
+	if certificate.Subject.String() != expected && certificate.Subject.CommonName != expected {
+		call.testState.Errorf("client certificate subject '%s' found where '%s' was expected", certificate.Subject, expected)
+	}
+	return call
+}
+
+// WithClientCertificateSAN asserts that the client presented a certificate with the specified subject alternative
+// name, either a DNS name, an email address, an IP address or a URI.
+func (call *Invocation) WithClientCertificateSAN(expected string) *Invocation {
+	certificate := call.GetClientCertificate()
+	if certificate == nil {
+		call.testState.Errorf("no client certificate found where one with SAN '%s' was expected", expected)
+		return call
+	}
+	names := append(append([]string{}, certificate.DNSNames...), certificate.EmailAddresses...)
+	for _, address := range certificate.IPAddresses {
+		names = append(names, address.String())
+	}
+	for _, uri := range certificate.URIs {
+		names = append(names, uri.String())
+	}
+	if !slices.Contains(names, expected) {
+		call.testState.Errorf("client certificate SANs %v found where '%s' was expected", names, expected)
+	}
+	return call
+}
+
+// WithTLSVersion asserts that the invocation request was made over the specified TLS version (e.g. tls.VersionTLS13).
+func (call *Invocation) WithTLSVersion(expected uint16) *Invocation {
+	if call.request.TLS == nil {
+		call.testState.Errorf("plain HTTP request found where %s was expected", tls.VersionName(expected))
+		return call
+	}
+	if call.request.TLS.Version != expected {
+		call.testState.Errorf("%s found where %s was expected", tls.VersionName(call.request.TLS.Version), tls.VersionName(expected))
+	}
+	return call
+}
+
 // WithPayload asserts that the invocation request contains the specified payload
 func (call *Invocation) WithPayload(expected []byte) *Invocation {
-	assertions.Equal(call.testState, expected, call.GetPayload())
+	call.assertEqual(expected, call.GetPayload(), func(value any) any {
+		return string(call.redaction.body(value.([]byte)))
+	})
 	return call
 }
 
 // WithStringPayload asserts that the invocation request contains the specified string payload
 func (call *Invocation) WithStringPayload(expected string) *Invocation {
-	assertions.Equal(call.testState, expected, string(call.GetPayload()))
+	call.assertEqual(expected, string(call.GetPayload()), func(value any) any {
+		return string(call.redaction.body([]byte(value.(string))))
+	})
 	return call
 }
 
@@ -114,7 +278,10 @@ func (call *Invocation) WithJSONPayload(expected any) *Invocation {
 
 	var actual any
 	call.ReadJSONPayload(&actual)
-	assertions.Equal(call.testState, untypedExpected, actual)
+	call.assertEqual(untypedExpected, actual, func(value any) any {
+		data, _ := json.Marshal(value)
+		return string(call.redaction.body(data))
+	})
 	return call
 }
 
@@ -138,7 +305,7 @@ func (call *Invocation) WithUrlEncodedFormPayload() InvocationRequestForm {
 func (call *Invocation) WithQueryValue(name string, value string) *Invocation {
 	query := call.request.URL.Query()
 	if query.Has(name) {
-		assertions.Equal(call.testState, value, query.Get(name))
+		call.assertQueryValue(name, value, query.Get(name))
 	} else {
 		call.testState.Errorf("query parameter '%s' not found", name)
 	}
@@ -149,7 +316,7 @@ func (call *Invocation) WithQueryValues(values map[string]string) *Invocation {
 	query := call.request.URL.Query()
 	for key, value := range values {
 		if query.Has(key) {
-			assertions.Equal(call.testState, value, query.Get(key))
+			call.assertQueryValue(key, value, query.Get(key))
 		} else {
 			call.testState.Errorf("query parameter '%s' not found", key)
 		}
@@ -161,7 +328,7 @@ func (call *Invocation) WithQueryValuesExactly(values map[string]string) *Invoca
 	query := call.request.URL.Query()
 	for key, value := range values {
 		if query.Has(key) {
-			assertions.Equal(call.testState, value, query.Get(key))
+			call.assertQueryValue(key, value, query.Get(key))
 		} else {
 			call.testState.Errorf("query parameter '%s' not found", key)
 		}
@@ -178,6 +345,24 @@ func (call *Invocation) WithQueryValuesExactly(values map[string]string) *Invoca
 	return call
 }
 
+func (call *Invocation) assertQueryValue(name string, expected string, actual string) {
+	call.assertEqual(expected, actual, func(value any) any {
+		return call.redaction.query(name, value.(string))
+	})
+}
+
+// assertEqual asserts that the actual value equals the expected one. When a redaction is configured, both values are
+// masked by the redact function before being printed in the failure message.
+func (call *Invocation) assertEqual(expected any, actual any, redact func(value any) any) {
+	if call.redaction == nil {
+		assertions.Equal(call.testState, expected, actual)
+		return
+	}
+	if !assertions.ObjectsAreEqual(expected, actual) {
+		call.testState.Errorf("Not equal:\nexpected: %v\nactual  : %v", redact(expected), redact(actual))
+	}
+}
+
 // InvocationRequestForm represents a form payload of an HTTP request made to the mock server.
 type InvocationRequestForm struct {
 	testState  T
@@ -192,7 +377,7 @@ func (form InvocationRequestForm) WithValues(expectedValues map[string]string) I
 		return form
 	}
 	for key, value := range expectedValues {
-		assertions.Equal(form.testState, value, form.formValues.Get(key))
+		form.assertValue(key, value)
 	}
 	return form
 }
@@ -205,7 +390,7 @@ func (form InvocationRequestForm) WithValuesExactly(expectedValues map[string]st
 	}
 	assertions.Equal(form.testState, len(expectedValues), len(form.formValues))
 	for key, value := range expectedValues {
-		assertions.Equal(form.testState, value, form.formValues.Get(key))
+		form.assertValue(key, value)
 	}
 	return form
 }
@@ -219,6 +404,14 @@ func (form InvocationRequestForm) WithValue(key string, value string) Invocation
 		form.testState.Error("not a form urlencoded request")
 		return form
 	}
-	assertions.Equal(form.testState, value, form.formValues.Get(key))
+	form.assertValue(key, value)
 	return form
 }
+
+// assertValue asserts that the form value of the given key equals the expected one, the values being redacted as
+// query values in the failure message.
+func (form InvocationRequestForm) assertValue(key string, expected string) {
+	form.invocation.assertEqual(expected, form.formValues.Get(key), func(value any) any {
+		return form.invocation.redaction.query(key, value.(string))
+	})
+}
@@ -2,6 +2,8 @@ package mockhttp
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"net/http"
 	"net/url"
 	"testing"
@@ -123,6 +125,150 @@ func Test_invocations(t *testing.T) {
 		})
 	})
 
+	t.Run("WithHost() should", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("pass with expected host", func(t *testing.T) {
+			t.Parallel()
+			request := buildRequest(t, http.MethodGet, "/endpoint")
+			request.Host = "api.partner.com:8443"
+
+			testState := testingmock.New(t)
+			invocation := newInvocation(request, testState)
+
+			invocation.WithHost("api.partner.com").WithHost("api.partner.com:8443")
+
+			testState.AssertDidNotFailed()
+		})
+
+		t.Run("fail when host differs", func(t *testing.T) {
+			t.Parallel()
+			request := buildRequest(t, http.MethodGet, "/endpoint")
+			request.Host = "api.partner.com:8443"
+
+			testState := testingmock.New(t)
+			invocation := newInvocation(request, testState)
+
+			invocation.WithHost("api.partner.com:443")
+
+			testState.AssertFailedWithError()
+		})
+	})
+
+	t.Run("WithClientCertificateSubject() should", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("pass with expected subject", func(t *testing.T) {
+			t.Parallel()
+			request := buildRequest(t, http.MethodGet, "/endpoint")
+			request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{newTestCertificate(t, "payments").Leaf}}
+
+			testState := testingmock.New(t)
+			invocation := newInvocation(request, testState)
+
+			invocation.WithClientCertificateSubject("CN=payments")
+
+			testState.AssertDidNotFailed()
+		})
+
+		t.Run("fail when subject differs", func(t *testing.T) {
+			t.Parallel()
+			request := buildRequest(t, http.MethodGet, "/endpoint")
+			request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{newTestCertificate(t, "intruder").Leaf}}
+
+			testState := testingmock.New(t)
+			invocation := newInvocation(request, testState)
+
+			invocation.WithClientCertificateSubject("payments")
+
+			testState.AssertFailedWithError()
+		})
+
+		t.Run("fail without client certificate", func(t *testing.T) {
+			t.Parallel()
+			request := buildRequest(t, http.MethodGet, "/endpoint")
+
+			testState := testingmock.New(t)
+			invocation := newInvocation(request, testState)
+
+			invocation.WithClientCertificateSubject("payments")
+
+			testState.AssertFailedWithError()
+		})
+	})
+
+	t.Run("WithClientCertificateSAN() should", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("pass with expected SAN", func(t *testing.T) {
+			t.Parallel()
+			request := buildRequest(t, http.MethodGet, "/endpoint")
+			request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{newTestCertificate(t, "payments", "payments.example.com").Leaf}}
+
+			testState := testingmock.New(t)
+			invocation := newInvocation(request, testState)
+
+			invocation.WithClientCertificateSAN("payments.example.com")
+
+			testState.AssertDidNotFailed()
+		})
+
+		t.Run("fail when SAN is missing", func(t *testing.T) {
+			t.Parallel()
+			request := buildRequest(t, http.MethodGet, "/endpoint")
+			request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{newTestCertificate(t, "payments").Leaf}}
+
+			testState := testingmock.New(t)
+			invocation := newInvocation(request, testState)
+
+			invocation.WithClientCertificateSAN("payments.example.com")
+
+			testState.AssertFailedWithError()
+		})
+	})
+
+	t.Run("WithTLSVersion() should", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("pass with expected version", func(t *testing.T) {
+			t.Parallel()
+			request := buildRequest(t, http.MethodGet, "/endpoint")
+			request.TLS = &tls.ConnectionState{Version: tls.VersionTLS12}
+
+			testState := testingmock.New(t)
+			invocation := newInvocation(request, testState)
+
+			invocation.WithTLSVersion(tls.VersionTLS12)
+
+			testState.AssertDidNotFailed()
+		})
+
+		t.Run("fail when version differs", func(t *testing.T) {
+			t.Parallel()
+			request := buildRequest(t, http.MethodGet, "/endpoint")
+			request.TLS = &tls.ConnectionState{Version: tls.VersionTLS12}
+
+			testState := testingmock.New(t)
+			invocation := newInvocation(request, testState)
+
+			invocation.WithTLSVersion(tls.VersionTLS13)
+
+			testState.AssertFailedWithError()
+		})
+
+		t.Run("fail with plain HTTP request", func(t *testing.T) {
+			t.Parallel()
+			request := buildRequest(t, http.MethodGet, "/endpoint")
+
+			testState := testingmock.New(t)
+			invocation := newInvocation(request, testState)
+
+			invocation.WithTLSVersion(tls.VersionTLS13)
+
+			testState.AssertFailedWithError()
+		})
+	})
+
 	t.Run("WithAuthHeader() should", func(t *testing.T) {
 		t.Parallel()
 
@@ -0,0 +1,475 @@
+package mockhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FromOpenAPI creates a new APIMock instance with a default stub for each operation of the given OpenAPI 3 document.
+// The spec is either the path of a YAML or JSON file or the document content itself.
+// Each stub responds with the declared success status and the example of the response (or a sample generated from
+// its schema). Operations can still be overridden using Stub.
+func FromOpenAPI[S string | []byte](testState T, spec S, options ...Option) *APIMock {
+	mockedAPI := API(testState, options...)
+	document, err := readOpenAPIDocument(spec)
+	if err != nil {
+		testState.Fatal(err)
+		return mockedAPI
+	}
+	mockedAPI.addSpec(document)
+	document.stub(mockedAPI)
+	return mockedAPI
+}
+
+func readOpenAPIDocument[S string | []byte](spec S) (*openAPIDocument, error) {
+	var data []byte
+	switch value := any(spec).(type) {
+	case string:
+		content, err := os.ReadFile(value)
+		if err != nil {
+			return nil, err
+		}
+		data = content
+	case []byte:
+		data = value
+	}
+	return parseOpenAPIDocument(data)
+}
+
+func parseOpenAPIDocument(data []byte) (*openAPIDocument, error) {
+	document := &openAPIDocument{}
+	if err := yaml.Unmarshal(data, document); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(document.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version '%s', only OpenAPI 3 documents are supported", document.OpenAPI)
+	}
+	document.compilePathTemplates()
+	return document, nil
+}
+
+// openAPIDocument is the subset of an OpenAPI 3 document used by the mock.
+type openAPIDocument struct {
+	OpenAPI    string                      `yaml:"openapi" json:"openapi"`
+	Info       openAPIInfo                 `yaml:"info" json:"info"`
+	Servers    []openAPIServer             `yaml:"servers,omitempty" json:"servers,omitempty"`
+	Paths      map[string]*openAPIPathItem `yaml:"paths" json:"paths"`
+	Components openAPIComponents           `yaml:"components,omitempty" json:"components,omitempty"`
+	// pathTemplates are the templates of the declared paths, exact paths first, compiled once the document is parsed.
+	pathTemplates []*pathTemplate
+}
+
+type openAPIInfo struct {
+	Title   string `yaml:"title" json:"title"`
+	Version string `yaml:"version" json:"version"`
+}
+
+type openAPIServer struct {
+	URL string `yaml:"url" json:"url"`
+}
+
+type openAPIComponents struct {
+	Schemas       map[string]*openAPISchema      `yaml:"schemas,omitempty" json:"schemas,omitempty"`
+	Parameters    map[string]*openAPIParameter   `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	RequestBodies map[string]*openAPIRequestBody `yaml:"requestBodies,omitempty" json:"requestBodies,omitempty"`
+	Responses     map[string]*openAPIResponse    `yaml:"responses,omitempty" json:"responses,omitempty"`
+}
+
+type openAPIPathItem struct {
+	Parameters []*openAPIParameter `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	Get        *openAPIOperation   `yaml:"get,omitempty" json:"get,omitempty"`
+	Put        *openAPIOperation   `yaml:"put,omitempty" json:"put,omitempty"`
+	Post       *openAPIOperation   `yaml:"post,omitempty" json:"post,omitempty"`
+	Delete     *openAPIOperation   `yaml:"delete,omitempty" json:"delete,omitempty"`
+	Options    *openAPIOperation   `yaml:"options,omitempty" json:"options,omitempty"`
+	Head       *openAPIOperation   `yaml:"head,omitempty" json:"head,omitempty"`
+	Patch      *openAPIOperation   `yaml:"patch,omitempty" json:"patch,omitempty"`
+	Trace      *openAPIOperation   `yaml:"trace,omitempty" json:"trace,omitempty"`
+}
+
+// operations returns the path item operations indexed by lower case HTTP method.
+func (item *openAPIPathItem) operations() map[string]*openAPIOperation {
+	operations := map[string]*openAPIOperation{}
+	for method, operation := range map[string]*openAPIOperation{
+		"get":     item.Get,
+		"put":     item.Put,
+		"post":    item.Post,
+		"delete":  item.Delete,
+		"options": item.Options,
+		"head":    item.Head,
+		"patch":   item.Patch,
+		"trace":   item.Trace,
+	} {
+		if operation != nil {
+			operations[method] = operation
+		}
+	}
+	return operations
+}
+
+// setOperation sets the path item operation for the given HTTP method.
+func (item *openAPIPathItem) setOperation(method string, operation *openAPIOperation) {
+	switch strings.ToLower(method) {
+	case "get":
+		item.Get = operation
+	case "put":
+		item.Put = operation
+	case "post":
+		item.Post = operation
+	case "delete":
+		item.Delete = operation
+	case "options":
+		item.Options = operation
+	case "head":
+		item.Head = operation
+	case "patch":
+		item.Patch = operation
+	case "trace":
+		item.Trace = operation
+	}
+}
+
+type openAPIOperation struct {
+	OperationID string                      `yaml:"operationId,omitempty" json:"operationId,omitempty"`
+	Parameters  []*openAPIParameter         `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `yaml:"requestBody,omitempty" json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `yaml:"responses" json:"responses"`
+}
+
+type openAPIParameter struct {
+	Ref      string         `yaml:"$ref,omitempty" json:"$ref,omitempty"`
+	Name     string         `yaml:"name,omitempty" json:"name,omitempty"`
+	In       string         `yaml:"in,omitempty" json:"in,omitempty"`
+	Required bool           `yaml:"required,omitempty" json:"required,omitempty"`
+	Schema   *openAPISchema `yaml:"schema,omitempty" json:"schema,omitempty"`
+}
+
+type openAPIRequestBody struct {
+	Ref      string                       `yaml:"$ref,omitempty" json:"$ref,omitempty"`
+	Required bool                         `yaml:"required,omitempty" json:"required,omitempty"`
+	Content  map[string]*openAPIMediaType `yaml:"content,omitempty" json:"content,omitempty"`
+}
+
+type openAPIResponse struct {
+	Ref         string                       `yaml:"$ref,omitempty" json:"$ref,omitempty"`
+	Description string                       `yaml:"description" json:"description"`
+	Headers     map[string]*openAPIParameter `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Content     map[string]*openAPIMediaType `yaml:"content,omitempty" json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema   *openAPISchema             `yaml:"schema,omitempty" json:"schema,omitempty"`
+	Example  any                        `yaml:"example,omitempty" json:"example,omitempty"`
+	Examples map[string]*openAPIExample `yaml:"examples,omitempty" json:"examples,omitempty"`
+}
+
+type openAPIExample struct {
+	Value any `yaml:"value,omitempty" json:"value,omitempty"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `yaml:"$ref,omitempty" json:"$ref,omitempty"`
+	Type                 openAPISchemaType         `yaml:"type,omitempty" json:"type,omitempty"`
+	Format               string                    `yaml:"format,omitempty" json:"format,omitempty"`
+	Nullable             bool                      `yaml:"nullable,omitempty" json:"nullable,omitempty"`
+	Enum                 []any                     `yaml:"enum,omitempty" json:"enum,omitempty"`
+	Default              any                       `yaml:"default,omitempty" json:"default,omitempty"`
+	Example              any                       `yaml:"example,omitempty" json:"example,omitempty"`
+	Properties           map[string]*openAPISchema `yaml:"properties,omitempty" json:"properties,omitempty"`
+	Required             []string                  `yaml:"required,omitempty" json:"required,omitempty"`
+	Items                *openAPISchema            `yaml:"items,omitempty" json:"items,omitempty"`
+	AllOf                []*openAPISchema          `yaml:"allOf,omitempty" json:"allOf,omitempty"`
+	OneOf                []*openAPISchema          `yaml:"oneOf,omitempty" json:"oneOf,omitempty"`
+	AnyOf                []*openAPISchema          `yaml:"anyOf,omitempty" json:"anyOf,omitempty"`
+	AdditionalProperties *openAPISchema            `yaml:"-" json:"additionalProperties,omitempty"`
+	NoAdditionalProps    bool                      `yaml:"-" json:"-"`
+	Minimum              *float64                  `yaml:"minimum,omitempty" json:"minimum,omitempty"`
+	Maximum              *float64                  `yaml:"maximum,omitempty" json:"maximum,omitempty"`
+	MinLength            *int                      `yaml:"minLength,omitempty" json:"minLength,omitempty"`
+	MaxLength            *int                      `yaml:"maxLength,omitempty" json:"maxLength,omitempty"`
+	MinItems             *int                      `yaml:"minItems,omitempty" json:"minItems,omitempty"`
+	MaxItems             *int                      `yaml:"maxItems,omitempty" json:"maxItems,omitempty"`
+	Pattern              string                    `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+}
+
+// UnmarshalYAML decodes the schema, handling additionalProperties being either a boolean or a schema.
+func (schema *openAPISchema) UnmarshalYAML(value *yaml.Node) error {
+	type plainSchema openAPISchema
+	if err := value.Decode((*plainSchema)(schema)); err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		if value.Content[i].Value != "additionalProperties" {
+			continue
+		}
+		additionalProperties := value.Content[i+1]
+		if additionalProperties.Kind == yaml.ScalarNode {
+			allowed, err := strconv.ParseBool(additionalProperties.Value)
+			if err != nil {
+				return fmt.Errorf("invalid additionalProperties value '%s'", additionalProperties.Value)
+			}
+			schema.NoAdditionalProps = !allowed
+			return nil
+		}
+		schema.AdditionalProperties = &openAPISchema{}
+		return additionalProperties.Decode(schema.AdditionalProperties)
+	}
+	return nil
+}
+
+// openAPISchemaType is a schema type, declared either as a single type (OpenAPI 3.0) or a list of types (OpenAPI 3.1).
+type openAPISchemaType []string
+
+// UnmarshalYAML decodes either a single type or a list of types.
+func (schemaType *openAPISchemaType) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*schemaType = openAPISchemaType{value.Value}
+		return nil
+	}
+	var types []string
+	if err := value.Decode(&types); err != nil {
+		return err
+	}
+	*schemaType = types
+	return nil
+}
+
+// MarshalJSON encodes a single type as a string and several types as a list.
+func (schemaType openAPISchemaType) MarshalJSON() ([]byte, error) {
+	if len(schemaType) == 1 {
+		return json.Marshal(schemaType[0])
+	}
+	return json.Marshal([]string(schemaType))
+}
+
+// has reports whether the schema type includes the given type.
+func (schemaType openAPISchemaType) has(name string) bool {
+	for _, declared := range schemaType {
+		if declared == name {
+			return true
+		}
+	}
+	return false
+}
+
+// basePath returns the path of the first declared server URL, without trailing slash.
+func (document *openAPIDocument) basePath() string {
+	if len(document.Servers) == 0 {
+		return ""
+	}
+	serverURL, err := url.Parse(document.Servers[0].URL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(serverURL.Path, "/")
+}
+
+// stub registers a default stub on the mocked API for each operation of the document.
+func (document *openAPIDocument) stub(mockedAPI *APIMock) {
+	basePath := document.basePath()
+	for path, item := range document.Paths {
+		for method, operation := range item.operations() {
+			statusCode, response := document.successResponse(operation)
+			builder := mockedAPI.Stub(method, basePath+path)
+			contentType, mediaType := preferredMediaType(response)
+			if mediaType == nil {
+				builder.WithStatusCode(statusCode)
+				continue
+			}
+			body, err := document.encodeExample(contentType, mediaType)
+			if err != nil {
+				mockedAPI.testState.Fatalf("cannot encode the example of operation %s %s: %v", strings.ToUpper(method), path, err)
+				continue
+			}
+			builder.WithBody(statusCode, body, contentType)
+		}
+	}
+}
+
+// successResponse returns the lowest declared 2xx response of the operation, falling back to the default response.
+func (document *openAPIDocument) successResponse(operation *openAPIOperation) (int, *openAPIResponse) {
+	statusCodes := make([]string, 0, len(operation.Responses))
+	for statusCode := range operation.Responses {
+		statusCodes = append(statusCodes, statusCode)
+	}
+	sort.Strings(statusCodes)
+	for _, statusCode := range statusCodes {
+		if statusCode == "2XX" || statusCode == "2xx" {
+			return http.StatusOK, document.resolveResponse(operation.Responses[statusCode])
+		}
+		if code, err := strconv.Atoi(statusCode); err == nil && code >= 200 && code < 300 {
+			return code, document.resolveResponse(operation.Responses[statusCode])
+		}
+	}
+	return http.StatusOK, document.resolveResponse(operation.Responses["default"])
+}
+
+// preferredMediaType returns the JSON media type of the response if any, otherwise the first declared one.
+func preferredMediaType(response *openAPIResponse) (string, *openAPIMediaType) {
+	if response == nil || len(response.Content) == 0 {
+		return "", nil
+	}
+	contentTypes := make([]string, 0, len(response.Content))
+	for contentType := range response.Content {
+		contentTypes = append(contentTypes, contentType)
+	}
+	sort.Strings(contentTypes)
+	for _, contentType := range contentTypes {
+		if isJSONContentType(contentType) {
+			return contentType, response.Content[contentType]
+		}
+	}
+	return contentTypes[0], response.Content[contentTypes[0]]
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// encodeExample returns the body of the media type example, generating one from its schema when none is declared.
+func (document *openAPIDocument) encodeExample(contentType string, mediaType *openAPIMediaType) ([]byte, error) {
+	example := mediaType.Example
+	if example == nil && len(mediaType.Examples) > 0 {
+		names := make([]string, 0, len(mediaType.Examples))
+		for name := range mediaType.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		example = mediaType.Examples[names[0]].Value
+	}
+	if example == nil {
+		example = document.sample(mediaType.Schema, 0)
+	}
+	if text, ok := example.(string); ok && !isJSONContentType(contentType) {
+		return []byte(text), nil
+	}
+	return json.Marshal(example)
+}
+
+// maxSampleDepth bounds the sample generation of recursive schemas.
+const maxSampleDepth = 8
+
+// sample generates a value conforming to the given schema.
+func (document *openAPIDocument) sample(schema *openAPISchema, depth int) any {
+	schema = document.resolveSchema(schema)
+	if schema == nil || depth > maxSampleDepth {
+		return nil
+	}
+	switch {
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	case len(schema.AllOf) > 0:
+		merged := map[string]any{}
+		for _, part := range schema.AllOf {
+			if object, ok := document.sample(part, depth+1).(map[string]any); ok {
+				for key, value := range object {
+					merged[key] = value
+				}
+			}
+		}
+		return merged
+	case len(schema.OneOf) > 0:
+		return document.sample(schema.OneOf[0], depth+1)
+	case len(schema.AnyOf) > 0:
+		return document.sample(schema.AnyOf[0], depth+1)
+	}
+
+	switch {
+	case schema.Type.has("object") || len(schema.Properties) > 0:
+		object := map[string]any{}
+		for name, property := range schema.Properties {
+			object[name] = document.sample(property, depth+1)
+		}
+		return object
+	case schema.Type.has("array"):
+		if schema.Items == nil {
+			return []any{}
+		}
+		return []any{document.sample(schema.Items, depth+1)}
+	case schema.Type.has("string"):
+		return sampleString(schema.Format)
+	case schema.Type.has("integer"):
+		if schema.Minimum != nil {
+			return int(*schema.Minimum)
+		}
+		return 0
+	case schema.Type.has("number"):
+		if schema.Minimum != nil {
+			return *schema.Minimum
+		}
+		return 0.0
+	case schema.Type.has("boolean"):
+		return true
+	}
+	return nil
+}
+
+func sampleString(format string) string {
+	switch format {
+	case "date-time":
+		return "2006-01-02T15:04:05Z"
+	case "date":
+		return "2006-01-02"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "uri", "url":
+		return "https://example.com"
+	case "byte":
+		return "c3RyaW5n"
+	}
+	return "string"
+}
+
+const (
+	schemasRefPrefix       = "#/components/schemas/"
+	parametersRefPrefix    = "#/components/parameters/"
+	requestBodiesRefPrefix = "#/components/requestBodies/"
+	responsesRefPrefix     = "#/components/responses/"
+)
+
+// resolveSchema follows the schema reference, if any.
+func (document *openAPIDocument) resolveSchema(schema *openAPISchema) *openAPISchema {
+	for i := 0; schema != nil && schema.Ref != "" && i < maxSampleDepth; i++ {
+		schema = document.Components.Schemas[strings.TrimPrefix(schema.Ref, schemasRefPrefix)]
+	}
+	return schema
+}
+
+// resolveParameter follows the parameter reference, if any.
+func (document *openAPIDocument) resolveParameter(parameter *openAPIParameter) *openAPIParameter {
+	if parameter != nil && parameter.Ref != "" {
+		return document.Components.Parameters[strings.TrimPrefix(parameter.Ref, parametersRefPrefix)]
+	}
+	return parameter
+}
+
+// resolveRequestBody follows the request body reference, if any.
+func (document *openAPIDocument) resolveRequestBody(requestBody *openAPIRequestBody) *openAPIRequestBody {
+	if requestBody != nil && requestBody.Ref != "" {
+		return document.Components.RequestBodies[strings.TrimPrefix(requestBody.Ref, requestBodiesRefPrefix)]
+	}
+	return requestBody
+}
+
+// resolveResponse follows the response reference, if any.
+func (document *openAPIDocument) resolveResponse(response *openAPIResponse) *openAPIResponse {
+	if response != nil && response.Ref != "" {
+		return document.Components.Responses[strings.TrimPrefix(response.Ref, responsesRefPrefix)]
+	}
+	return response
+}
@@ -0,0 +1,296 @@
+package mockhttp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ignoredExportHeaders are the request headers handled by HTTP clients and not documented as operation parameters.
+var ignoredExportHeaders = map[string]bool{
+	"Accept":          true,
+	"Accept-Encoding": true,
+	"Authorization":   true,
+	"Connection":      true,
+	"Content-Length":  true,
+	"Content-Type":    true,
+	"User-Agent":      true,
+}
+
+// ExportOpenAPI writes an OpenAPI 3 document (JSON encoded) inferred from the registered stubs and the recorded
+// invocations: paths, methods, query parameters, header names, and JSON schemas of the request and response bodies.
+// The document is a skeleton meant to be reviewed and completed. Stubs which cannot be described as an OpenAPI
+// operation, i.e. registered for a virtual host (see APIMock.Host), for any method or for a regular expression path
+// (see APIMock.ImportWireMockMappings), are not exported.
+func (mockedAPI *APIMock) ExportOpenAPI(writer io.Writer) error {
+	document := mockedAPI.inferOpenAPIDocument()
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+func (mockedAPI *APIMock) inferOpenAPIDocument() *openAPIDocument {
+	mockedAPI.mu.Lock()
+	defer mockedAPI.mu.Unlock()
+
+	grouped := map[HTTPCall][]*Invocation{}
+	for call := range mockedAPI.calls {
+		if mockedAPI.isExportable(call) {
+			grouped[call] = nil
+		}
+	}
+	for call, invocations := range mockedAPI.invocations {
+		operationCall := call
+		if _, stubbed := mockedAPI.calls[call]; !stubbed {
+			for _, template := range mockedAPI.templates {
+				if template.call.Method == call.Method && template.pattern.MatchString(call.Path) {
+					operationCall = template.call
+					break
+				}
+			}
+		}
+		if mockedAPI.isExportable(operationCall) {
+			grouped[operationCall] = append(grouped[operationCall], invocations...)
+		}
+	}
+
+	document := &openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:   "Mocked API",
+			Version: "1.0.0",
+		},
+		Paths: map[string]*openAPIPathItem{},
+	}
+	for call, invocations := range grouped {
+		item, ok := document.Paths[call.Path]
+		if !ok {
+			item = &openAPIPathItem{}
+			document.Paths[call.Path] = item
+		}
+		item.setOperation(call.Method, inferOperation(call, invocations))
+	}
+	return document
+}
+
+// isExportable returns whether the stubbed call can be described as an OpenAPI operation.
+func (mockedAPI *APIMock) isExportable(call HTTPCall) bool {
+	if call.Host != "" || call.Method == anyMethod {
+		return false
+	}
+	for _, template := range mockedAPI.templates {
+		if template.call == call && template.regexp {
+			return false
+		}
+	}
+	return true
+}
+
+func inferOperation(call HTTPCall, invocations []*Invocation) *openAPIOperation {
+	operation := &openAPIOperation{
+		Responses: map[string]*openAPIResponse{},
+	}
+
+	for _, name := range pathParameterRegexp.FindAllStringSubmatch(call.Path, -1) {
+		operation.Parameters = append(operation.Parameters, &openAPIParameter{
+			Name:     name[1],
+			In:       "path",
+			Required: true,
+			Schema:   &openAPISchema{Type: openAPISchemaType{"string"}},
+		})
+	}
+	operation.Parameters = append(operation.Parameters, inferParameters("query", invocations, func(invocation *Invocation) map[string][]string {
+		return invocation.GetRequest().URL.Query()
+	})...)
+	operation.Parameters = append(operation.Parameters, inferParameters("header", invocations, func(invocation *Invocation) map[string][]string {
+		headers := map[string][]string{}
+		for name, values := range invocation.GetRequest().Header {
+			if !ignoredExportHeaders[name] {
+				headers[name] = values
+			}
+		}
+		return headers
+	})...)
+
+	requestContent := map[string]*openAPIMediaType{}
+	for _, invocation := range invocations {
+		mergeContent(requestContent, invocation.GetRequest().Header.Get("Content-Type"), invocation.GetPayload())
+		response := invocation.GetResponse()
+		if response == nil {
+			continue
+		}
+		statusCode := strconv.Itoa(response.StatusCode)
+		declared, ok := operation.Responses[statusCode]
+		if !ok {
+			declared = &openAPIResponse{
+				Description: http.StatusText(response.StatusCode),
+				Content:     map[string]*openAPIMediaType{},
+			}
+			operation.Responses[statusCode] = declared
+		}
+		mergeContent(declared.Content, response.Header.Get("Content-Type"), invocation.GetResponsePayload())
+	}
+	if len(requestContent) > 0 {
+		operation.RequestBody = &openAPIRequestBody{Content: requestContent}
+	}
+	if len(operation.Responses) == 0 {
+		operation.Responses["default"] = &openAPIResponse{Description: "stubbed response"}
+	}
+	return operation
+}
+
+// inferParameters returns the parameters found in the invocations, required when present in all of them.
+func inferParameters(in string, invocations []*Invocation, values func(invocation *Invocation) map[string][]string) []*openAPIParameter {
+	occurrences := map[string]int{}
+	schemas := map[string]*openAPISchema{}
+	for _, invocation := range invocations {
+		for name, parameterValues := range values(invocation) {
+			occurrences[name]++
+			for _, value := range parameterValues {
+				schemas[name] = mergeSchemas(schemas[name], inferParameterSchema(value))
+			}
+		}
+	}
+
+	names := make([]string, 0, len(occurrences))
+	for name := range occurrences {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parameters := make([]*openAPIParameter, 0, len(names))
+	for _, name := range names {
+		parameters = append(parameters, &openAPIParameter{
+			Name:     name,
+			In:       in,
+			Required: occurrences[name] == len(invocations),
+			Schema:   schemas[name],
+		})
+	}
+	return parameters
+}
+
+func inferParameterSchema(value string) *openAPISchema {
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return &openAPISchema{Type: openAPISchemaType{"integer"}}
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return &openAPISchema{Type: openAPISchemaType{"number"}}
+	}
+	if _, err := strconv.ParseBool(value); err == nil {
+		return &openAPISchema{Type: openAPISchemaType{"boolean"}}
+	}
+	return &openAPISchema{Type: openAPISchemaType{"string"}}
+}
+
+// mergeContent adds the body to the content map, inferring its schema when it is JSON.
+func mergeContent(content map[string]*openAPIMediaType, contentType string, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+	declared, ok := content[mediaType]
+	if !ok {
+		declared = &openAPIMediaType{}
+		content[mediaType] = declared
+	}
+	if !isJSONContentType(mediaType) {
+		declared.Schema = &openAPISchema{Type: openAPISchemaType{"string"}}
+		return
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return
+	}
+	declared.Schema = mergeSchemas(declared.Schema, inferSchema(value))
+}
+
+// inferSchema returns the schema describing the given decoded JSON value.
+func inferSchema(value any) *openAPISchema {
+	switch typedValue := value.(type) {
+	case map[string]any:
+		schema := &openAPISchema{
+			Type:       openAPISchemaType{"object"},
+			Properties: map[string]*openAPISchema{},
+		}
+		for name, property := range typedValue {
+			schema.Properties[name] = inferSchema(property)
+			schema.Required = append(schema.Required, name)
+		}
+		sort.Strings(schema.Required)
+		return schema
+	case []any:
+		schema := &openAPISchema{Type: openAPISchemaType{"array"}}
+		for _, item := range typedValue {
+			schema.Items = mergeSchemas(schema.Items, inferSchema(item))
+		}
+		if schema.Items == nil {
+			schema.Items = &openAPISchema{}
+		}
+		return schema
+	case string:
+		return &openAPISchema{Type: openAPISchemaType{"string"}}
+	case bool:
+		return &openAPISchema{Type: openAPISchemaType{"boolean"}}
+	case float64:
+		if typedValue == float64(int64(typedValue)) {
+			return &openAPISchema{Type: openAPISchemaType{"integer"}}
+		}
+		return &openAPISchema{Type: openAPISchemaType{"number"}}
+	}
+	return &openAPISchema{Nullable: true}
+}
+
+// mergeSchemas returns a schema describing the values of both schemas.
+func mergeSchemas(schema *openAPISchema, other *openAPISchema) *openAPISchema {
+	if schema == nil {
+		return other
+	}
+	if other == nil {
+		return schema
+	}
+	nullable := schema.Nullable || other.Nullable
+	switch {
+	case len(schema.Type) == 0:
+		merged := *other
+		merged.Nullable = nullable
+		return &merged
+	case len(other.Type) == 0:
+		merged := *schema
+		merged.Nullable = nullable
+		return &merged
+	case schema.Type.has("integer") && other.Type.has("number"), schema.Type.has("number") && other.Type.has("integer"):
+		return &openAPISchema{Type: openAPISchemaType{"number"}, Nullable: nullable}
+	case schema.Type[0] != other.Type[0]:
+		return &openAPISchema{Nullable: nullable}
+	}
+
+	merged := &openAPISchema{Type: schema.Type, Nullable: nullable}
+	switch {
+	case schema.Type.has("object"):
+		merged.Properties = map[string]*openAPISchema{}
+		for name, property := range schema.Properties {
+			merged.Properties[name] = mergeSchemas(property, other.Properties[name])
+		}
+		for name, property := range other.Properties {
+			if _, ok := merged.Properties[name]; !ok {
+				merged.Properties[name] = property
+			}
+		}
+		for _, name := range schema.Required {
+			for _, otherName := range other.Required {
+				if name == otherName {
+					merged.Required = append(merged.Required, name)
+				}
+			}
+		}
+	case schema.Type.has("array"):
+		merged.Items = mergeSchemas(schema.Items, other.Items)
+	}
+	return merged
+}
@@ -0,0 +1,117 @@
+package mockhttp
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ExportOpenAPI(t *testing.T) {
+	t.Parallel()
+
+	t.Run("infers the document from stubs and invocations", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.
+			Stub(http.MethodGet, "/users/{id}").
+			WithJSON(http.StatusOK, map[string]any{"id": 1, "name": "John", "tags": []string{"admin"}}).
+			Stub(http.MethodPost, "/users").
+			WithStatusCode(http.StatusCreated).
+			Stub(http.MethodDelete, "/users/{id}").
+			WithStatusCode(http.StatusNoContent)
+
+		request, err := http.NewRequest(http.MethodGet, mockedAPI.GetURL().String()+"/users/1?expand=true&page=2", nil)
+		require.NoError(t, err)
+		request.Header.Set("X-Request-Id", "abc")
+		_, err = http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		_, err = http.Get(mockedAPI.GetURL().String() + "/users/2?page=3")
+		require.NoError(t, err)
+		_, err = http.Post(mockedAPI.GetURL().String()+"/users", "application/json", bytes.NewBufferString(`{"name": "Jane", "age": 42.5}`))
+		require.NoError(t, err)
+
+		// Act
+		output := &bytes.Buffer{}
+		err = mockedAPI.ExportOpenAPI(output)
+
+		// Assert
+		require.NoError(t, err)
+		testState.AssertDidNotFailed()
+		document, err := parseOpenAPIDocument(output.Bytes())
+		require.NoError(t, err)
+
+		assert := assertions.New(t)
+		require.Contains(t, document.Paths, "/users/{id}")
+		require.Contains(t, document.Paths, "/users")
+
+		getUser := document.Paths["/users/{id}"].Get
+		require.NotNil(t, getUser)
+		require.Len(t, getUser.Parameters, 4)
+		assert.Equal("id", getUser.Parameters[0].Name)
+		assert.Equal("path", getUser.Parameters[0].In)
+		assert.Equal("expand", getUser.Parameters[1].Name)
+		assert.False(getUser.Parameters[1].Required)
+		assert.Equal(openAPISchemaType{"boolean"}, getUser.Parameters[1].Schema.Type)
+		assert.Equal("page", getUser.Parameters[2].Name)
+		assert.True(getUser.Parameters[2].Required)
+		assert.Equal(openAPISchemaType{"integer"}, getUser.Parameters[2].Schema.Type)
+		assert.Equal("X-Request-Id", getUser.Parameters[3].Name)
+		assert.Equal("header", getUser.Parameters[3].In)
+
+		require.Contains(t, getUser.Responses, "200")
+		userSchema := getUser.Responses["200"].Content["application/json"].Schema
+		assert.Equal(openAPISchemaType{"object"}, userSchema.Type)
+		assert.Equal([]string{"id", "name", "tags"}, userSchema.Required)
+		assert.Equal(openAPISchemaType{"integer"}, userSchema.Properties["id"].Type)
+		assert.Equal(openAPISchemaType{"array"}, userSchema.Properties["tags"].Type)
+		assert.Equal(openAPISchemaType{"string"}, userSchema.Properties["tags"].Items.Type)
+
+		createUser := document.Paths["/users"].Post
+		require.NotNil(t, createUser)
+		require.NotNil(t, createUser.RequestBody)
+		requestSchema := createUser.RequestBody.Content["application/json"].Schema
+		assert.Equal(openAPISchemaType{"number"}, requestSchema.Properties["age"].Type)
+		assert.Contains(createUser.Responses, "201")
+
+		deleteUser := document.Paths["/users/{id}"].Delete
+		require.NotNil(t, deleteUser)
+		assert.Contains(deleteUser.Responses, "default")
+	})
+
+	t.Run("skips the stubs which are not OpenAPI operations", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/users").WithStatusCode(http.StatusOK)
+		mockedAPI.Host("admin.example.com").Stub(http.MethodGet, "/users").WithStatusCode(http.StatusForbidden)
+		mockedAPI.ImportWireMockMappings(bytes.NewBufferString(`{"mappings": [
+			{"request": {"method": "ANY", "url": "/health"}, "response": {"status": 200}},
+			{"request": {"method": "GET", "urlPathPattern": "/users/[0-9]{3}"}, "response": {"status": 200}}
+		]}`))
+		_, err := http.Get(mockedAPI.GetURL().String() + "/users/123")
+		require.NoError(t, err)
+
+		// Act
+		output := &bytes.Buffer{}
+		err = mockedAPI.ExportOpenAPI(output)
+
+		// Assert
+		require.NoError(t, err)
+		testState.AssertDidNotFailed()
+		document, err := parseOpenAPIDocument(output.Bytes())
+		require.NoError(t, err)
+		assert := assertions.New(t)
+		assert.Len(document.Paths, 1)
+		require.Contains(t, document.Paths, "/users")
+		assert.Contains(document.Paths["/users"].Get.Responses, "default")
+	})
+}
@@ -0,0 +1,240 @@
+package mockhttp
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const petstoreSpec = `
+openapi: 3.0.3
+info:
+  title: Petstore
+  version: 1.0.0
+servers:
+  - url: https://petstore.example.com/v1
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            maximum: 100
+      responses:
+        "200":
+          description: the pets
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: "#/components/schemas/Pet"
+    post:
+      operationId: createPet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/Pet"
+      responses:
+        "201":
+          description: created
+        default:
+          description: error
+  /pets/{petId}:
+    get:
+      operationId: showPetById
+      parameters:
+        - name: petId
+          in: path
+          required: true
+          schema:
+            type: integer
+      responses:
+        "200":
+          description: the pet
+          content:
+            application/json:
+              example:
+                id: 1
+                name: Rex
+              schema:
+                $ref: "#/components/schemas/Pet"
+        "404":
+          description: not found
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [id, name]
+      properties:
+        id:
+          type: integer
+        name:
+          type: string
+        tag:
+          type: string
+          enum: [dog, cat]
+`
+
+func Test_FromOpenAPI(t *testing.T) {
+	t.Parallel()
+
+	getBody := func(t *testing.T, mockedAPI *APIMock, method string, path string) (*http.Response, string) {
+		request, err := http.NewRequest(method, mockedAPI.GetURL().String()+path, nil)
+		require.NoError(t, err)
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response, string(body)
+	}
+
+	t.Run("stubs operations with their declared example", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := FromOpenAPI(testState, []byte(petstoreSpec))
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		response, body := getBody(t, mockedAPI, http.MethodGet, "/v1/pets/42")
+
+		// Assert
+		testState.AssertDidNotFailed()
+		assert := assertions.New(t)
+		assert.Equal(http.StatusOK, response.StatusCode)
+		assert.Equal("application/json", response.Header.Get("Content-Type"))
+		assert.JSONEq(`{"id": 1, "name": "Rex"}`, body)
+		mockedAPI.Verify(http.MethodGet, "/v1/pets/42").HasBeenCalledOnce()
+	})
+
+	t.Run("stubs operations with a sample generated from the response schema", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := FromOpenAPI(testState, []byte(petstoreSpec))
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		response, body := getBody(t, mockedAPI, http.MethodGet, "/v1/pets")
+
+		// Assert
+		testState.AssertDidNotFailed()
+		assert := assertions.New(t)
+		assert.Equal(http.StatusOK, response.StatusCode)
+		assert.JSONEq(`[{"id": 0, "name": "string", "tag": "dog"}]`, body)
+	})
+
+	t.Run("stubs operations without content with their success status", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := FromOpenAPI(testState, []byte(petstoreSpec))
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		response, _ := getBody(t, mockedAPI, http.MethodPost, "/v1/pets")
+
+		// Assert
+		testState.AssertDidNotFailed()
+		assertions.Equal(t, http.StatusCreated, response.StatusCode)
+	})
+
+	t.Run("allows to override operations", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := FromOpenAPI(testState, []byte(petstoreSpec))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/v1/pets/{petId}").WithStatusCode(http.StatusNotFound)
+
+		// Act
+		response, _ := getBody(t, mockedAPI, http.MethodGet, "/v1/pets/42")
+
+		// Assert
+		testState.AssertDidNotFailed()
+		assertions.Equal(t, http.StatusNotFound, response.StatusCode)
+	})
+
+	t.Run("reads the spec from a file", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		specPath := filepath.Join(t.TempDir(), "petstore.yaml")
+		require.NoError(t, os.WriteFile(specPath, []byte(petstoreSpec), 0o600))
+		testState := testingmock.New(t)
+		mockedAPI := FromOpenAPI(testState, specPath)
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		response, _ := getBody(t, mockedAPI, http.MethodGet, "/v1/pets/42")
+
+		// Assert
+		testState.AssertDidNotFailed()
+		assertions.Equal(t, http.StatusOK, response.StatusCode)
+	})
+
+	t.Run("fails when the spec cannot be read", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+
+		// Act
+		mockedAPI := FromOpenAPI(testState, filepath.Join(t.TempDir(), "missing.yaml"))
+		t.Cleanup(mockedAPI.Close)
+
+		// Assert
+		testState.AssertFailedWithFatal()
+	})
+
+	t.Run("fails when the spec is not an OpenAPI 3 document", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+
+		// Act
+		mockedAPI := FromOpenAPI(testState, []byte(`swagger: "2.0"`))
+		t.Cleanup(mockedAPI.Close)
+
+		// Assert
+		testState.AssertFailedWithFatal()
+	})
+	t.Run("fails when an example cannot be encoded", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+
+		// Act
+		mockedAPI := FromOpenAPI(testState, []byte(`
+openapi: 3.0.3
+info:
+  title: Rates
+  version: 1.0.0
+paths:
+  /rates:
+    get:
+      responses:
+        "200":
+          description: the exchange rates
+          content:
+            application/json:
+              example:
+                usd: .nan
+`))
+		t.Cleanup(mockedAPI.Close)
+
+		// Assert
+		testState.AssertFailedWithFatalMessage("cannot encode the example of operation GET /rates: " +
+			"json: unsupported value: NaN")
+	})
+}
@@ -0,0 +1,454 @@
+package mockhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// WithOpenAPIValidation validates every invocation against the given OpenAPI 3 document, the spec being either the
+// path of a YAML or JSON file or the document content itself. Requests are checked against their operation (path,
+// method, parameters, required headers and body schema) and stubbed responses against the declared response schema.
+// Each contract violation fails the test. Invocations outside the path of the first server URL of the document (e.g.
+// /v10/pets for a https://api.example.com/v1 server) are not validated.
+func WithOpenAPIValidation[S string | []byte](spec S) Option {
+	return func(mockedAPI *APIMock) {
+		document, err := readOpenAPIDocument(spec)
+		if err != nil {
+			mockedAPI.testState.Fatal(err)
+			return
+		}
+		mockedAPI.contract = document
+		mockedAPI.addSpec(document)
+	}
+}
+
+// openAPIOperationMatch is the operation matching a request, along with its path parameters values.
+type openAPIOperationMatch struct {
+	path           string
+	item           *openAPIPathItem
+	operation      *openAPIOperation
+	pathParameters map[string]string
+}
+
+// findOperation returns the operation declared for the given method and path, nil if there is none.
+func (document *openAPIDocument) findOperation(method string, path string) *openAPIOperationMatch {
+	if !document.inBasePath(path) {
+		return nil
+	}
+	path = strings.TrimPrefix(path, document.basePath())
+
+	for _, template := range document.pathTemplates {
+		pathParameters, ok := template.match(path)
+		if !ok {
+			continue
+		}
+		item := document.Paths[template.call.Path]
+		operation := item.operations()[strings.ToLower(method)]
+		if operation == nil {
+			continue
+		}
+		return &openAPIOperationMatch{
+			path:           template.call.Path,
+			item:           item,
+			operation:      operation,
+			pathParameters: pathParameters,
+		}
+	}
+	return nil
+}
+
+// inBasePath returns whether the path is the path of the first server URL of the document or one of its sub-paths.
+func (document *openAPIDocument) inBasePath(path string) bool {
+	basePath := document.basePath()
+	return basePath == "" || path == basePath || strings.HasPrefix(path, basePath+"/")
+}
+
+// compilePathTemplates compiles the templates of the declared paths, exact paths being tried first so that
+// /pets/mine takes precedence over /pets/{petId}.
+func (document *openAPIDocument) compilePathTemplates() {
+	paths := make([]string, 0, len(document.Paths))
+	for declaredPath := range document.Paths {
+		paths = append(paths, declaredPath)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		iTemplate, jTemplate := isPathTemplate(paths[i]), isPathTemplate(paths[j])
+		if iTemplate != jTemplate {
+			return jTemplate
+		}
+		return paths[i] < paths[j]
+	})
+	document.pathTemplates = make([]*pathTemplate, 0, len(paths))
+	for _, declaredPath := range paths {
+		document.pathTemplates = append(document.pathTemplates, newPathTemplate(HTTPCall{Path: declaredPath}))
+	}
+}
+
+// parameters returns the operation parameters, including the ones declared at the path level.
+func (document *openAPIDocument) parameters(match *openAPIOperationMatch) []*openAPIParameter {
+	parameters := map[string]*openAPIParameter{}
+	for _, declared := range append(append([]*openAPIParameter{}, match.item.Parameters...), match.operation.Parameters...) {
+		parameter := document.resolveParameter(declared)
+		if parameter != nil {
+			parameters[parameter.In+":"+parameter.Name] = parameter
+		}
+	}
+	keys := make([]string, 0, len(parameters))
+	for key := range parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]*openAPIParameter, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, parameters[key])
+	}
+	return result
+}
+
+// verifyRequest fails the test if the invocation request does not comply with the document.
+func (document *openAPIDocument) verifyRequest(testState T, invocation *Invocation) {
+	request := invocation.GetRequest()
+	if !document.inBasePath(request.URL.Path) {
+		return
+	}
+	violations := document.validateRequest(request, invocation.GetPayload())
+	if len(violations) > 0 {
+		testState.Errorf("OpenAPI contract violation for request %s %s:\n- %s",
+			request.Method, request.URL.Path, strings.Join(violations, "\n- "))
+	}
+}
+
+// verifyResponse fails the test if the recorded response of the invocation does not comply with the document.
+func (document *openAPIDocument) verifyResponse(testState T, invocation *Invocation, recorder *responseRecorder) {
+	request := invocation.GetRequest()
+	match := document.findOperation(request.Method, request.URL.Path)
+	if match == nil {
+		return
+	}
+	violations := document.validateResponse(match.operation, recorder.getStatusCode(), recorder.Header(), recorder.body.Bytes())
+	if len(violations) > 0 {
+		testState.Errorf("OpenAPI contract violation for response of %s %s:\n- %s",
+			request.Method, request.URL.Path, strings.Join(violations, "\n- "))
+	}
+}
+
+func (document *openAPIDocument) validateRequest(request *http.Request, payload []byte) []string {
+	match := document.findOperation(request.Method, request.URL.Path)
+	if match == nil {
+		return []string{fmt.Sprintf("no operation declared for %s %s", request.Method, request.URL.Path)}
+	}
+
+	var violations []string
+	query := request.URL.Query()
+	for _, parameter := range document.parameters(match) {
+		var values []string
+		switch parameter.In {
+		case "path":
+			if value, ok := match.pathParameters[parameter.Name]; ok {
+				values = []string{value}
+			}
+		case "query":
+			values = query[parameter.Name]
+		case "header":
+			values = request.Header.Values(parameter.Name)
+		case "cookie":
+			if cookie, err := request.Cookie(parameter.Name); err == nil {
+				values = []string{cookie.Value}
+			}
+		}
+		location := fmt.Sprintf("%s parameter '%s'", parameter.In, parameter.Name)
+		if len(values) == 0 {
+			if parameter.Required || parameter.In == "path" {
+				violations = append(violations, location+" is required")
+			}
+			continue
+		}
+		violations = append(violations, document.validateParameter(parameter.Schema, values, location)...)
+	}
+
+	requestBody := document.resolveRequestBody(match.operation.RequestBody)
+	switch {
+	case requestBody == nil && len(payload) > 0:
+		violations = append(violations, "request body is not declared")
+	case requestBody == nil:
+	case len(payload) == 0:
+		if requestBody.Required {
+			violations = append(violations, "request body is required")
+		}
+	default:
+		violations = append(violations,
+			document.validateContent(requestBody.Content, request.Header.Get("Content-Type"), payload, "request body")...)
+	}
+	return violations
+}
+
+func (document *openAPIDocument) validateResponse(operation *openAPIOperation, statusCode int, header http.Header, body []byte) []string {
+	response, ok := operation.Responses[strconv.Itoa(statusCode)]
+	if !ok {
+		response, ok = findStatusRangeResponse(operation.Responses, statusCode)
+	}
+	if !ok {
+		response, ok = operation.Responses["default"]
+	}
+	if !ok {
+		return []string{fmt.Sprintf("response status %d is not declared", statusCode)}
+	}
+	response = document.resolveResponse(response)
+	if response == nil {
+		return nil
+	}
+
+	var violations []string
+	for name, declared := range response.Headers {
+		headerParameter := document.resolveParameter(declared)
+		if headerParameter != nil && headerParameter.Required && header.Get(name) == "" {
+			violations = append(violations, fmt.Sprintf("response header '%s' is required", name))
+		}
+	}
+	if len(body) == 0 {
+		return violations
+	}
+	if len(response.Content) == 0 {
+		return append(violations, fmt.Sprintf("response body is not declared for status %d", statusCode))
+	}
+	return append(violations, document.validateContent(response.Content, header.Get("Content-Type"), body, "response body")...)
+}
+
+// findStatusRangeResponse returns the response declared for the range of the status code (e.g. "2XX"), the range
+// being written in uppercase or lowercase.
+func findStatusRangeResponse(responses map[string]*openAPIResponse, statusCode int) (*openAPIResponse, bool) {
+	statusRange := fmt.Sprintf("%dXX", statusCode/100)
+	for key, response := range responses {
+		if strings.ToUpper(key) == statusRange {
+			return response, true
+		}
+	}
+	return nil, false
+}
+
+// validateContent validates the body against the media type matching the given content type.
+func (document *openAPIDocument) validateContent(content map[string]*openAPIMediaType, contentType string, body []byte, location string) []string {
+	mediaType := findMediaType(content, contentType)
+	if mediaType == nil {
+		return []string{fmt.Sprintf("%s content type '%s' is not declared", location, contentType)}
+	}
+	if mediaType.Schema == nil || !isJSONContentType(contentType) {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []string{fmt.Sprintf("%s is not valid JSON: %s", location, err)}
+	}
+	return document.validateValue(mediaType.Schema, value, location)
+}
+
+// findMediaType returns the declared media type matching the content type, supporting wildcards like application/*.
+func findMediaType(content map[string]*openAPIMediaType, contentType string) *openAPIMediaType {
+	actual, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		actual = contentType
+	}
+	var wildcard *openAPIMediaType
+	for declared, mediaType := range content {
+		declaredType, _, err := mime.ParseMediaType(declared)
+		if err != nil {
+			declaredType = declared
+		}
+		switch {
+		case declaredType == actual:
+			return mediaType
+		case declaredType == "*/*",
+			strings.HasSuffix(declaredType, "/*") && strings.HasPrefix(actual, strings.TrimSuffix(declaredType, "*")):
+			wildcard = mediaType
+		}
+	}
+	return wildcard
+}
+
+// validateParameter converts the raw parameter values according to the schema type then validates them.
+func (document *openAPIDocument) validateParameter(schema *openAPISchema, values []string, location string) []string {
+	schema = document.resolveSchema(schema)
+	if schema == nil {
+		return nil
+	}
+	if schema.Type.has("array") {
+		if len(values) == 1 && strings.Contains(values[0], ",") {
+			values = strings.Split(values[0], ",")
+		}
+		items := make([]any, 0, len(values))
+		for _, value := range values {
+			items = append(items, document.convertParameter(schema.Items, value))
+		}
+		return document.validateValue(schema, items, location)
+	}
+	return document.validateValue(schema, document.convertParameter(schema, values[0]), location)
+}
+
+// convertParameter converts a raw parameter value to the JSON type declared by the schema, if possible.
+func (document *openAPIDocument) convertParameter(schema *openAPISchema, value string) any {
+	schema = document.resolveSchema(schema)
+	if schema == nil {
+		return value
+	}
+	switch {
+	case schema.Type.has("integer"), schema.Type.has("number"):
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	case schema.Type.has("boolean"):
+		if boolean, err := strconv.ParseBool(value); err == nil {
+			return boolean
+		}
+	}
+	return value
+}
+
+// validateValue validates a decoded JSON value against the schema, returning the violations found.
+func (document *openAPIDocument) validateValue(schema *openAPISchema, value any, location string) []string {
+	schema = document.resolveSchema(schema)
+	if schema == nil {
+		return nil
+	}
+
+	if value == nil {
+		if schema.Nullable || schema.Type.has("null") || len(schema.Type) == 0 {
+			return nil
+		}
+		return []string{location + " must not be null"}
+	}
+
+	var violations []string
+	for _, part := range schema.AllOf {
+		violations = append(violations, document.validateValue(part, value, location)...)
+	}
+	if len(schema.OneOf) > 0 {
+		valid := 0
+		for _, part := range schema.OneOf {
+			if len(document.validateValue(part, value, location)) == 0 {
+				valid++
+			}
+		}
+		if valid != 1 {
+			violations = append(violations, fmt.Sprintf("%s must match exactly one schema of oneOf (matches %d)", location, valid))
+		}
+	}
+	if len(schema.AnyOf) > 0 {
+		valid := false
+		for _, part := range schema.AnyOf {
+			if len(document.validateValue(part, value, location)) == 0 {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			violations = append(violations, location+" must match at least one schema of anyOf")
+		}
+	}
+	if len(schema.Enum) > 0 && !enumContains(schema.Enum, value) {
+		violations = append(violations, fmt.Sprintf("%s must be one of %v", location, schema.Enum))
+	}
+
+	if len(schema.Type) > 0 && !matchesSchemaType(schema.Type, value) {
+		return append(violations, fmt.Sprintf("%s must be of type %s", location, strings.Join(schema.Type, " or ")))
+	}
+
+	switch typedValue := value.(type) {
+	case map[string]any:
+		violations = append(violations, document.validateObject(schema, typedValue, location)...)
+	case []any:
+		if schema.MinItems != nil && len(typedValue) < *schema.MinItems {
+			violations = append(violations, fmt.Sprintf("%s must contain at least %d items", location, *schema.MinItems))
+		}
+		if schema.MaxItems != nil && len(typedValue) > *schema.MaxItems {
+			violations = append(violations, fmt.Sprintf("%s must contain at most %d items", location, *schema.MaxItems))
+		}
+		for i, item := range typedValue {
+			violations = append(violations, document.validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", location, i))...)
+		}
+	case string:
+		length := utf8.RuneCountInString(typedValue)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			violations = append(violations, fmt.Sprintf("%s must be at least %d characters long", location, *schema.MinLength))
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			violations = append(violations, fmt.Sprintf("%s must be at most %d characters long", location, *schema.MaxLength))
+		}
+		if schema.Pattern != "" {
+			if pattern, err := regexp.Compile(schema.Pattern); err == nil && !pattern.MatchString(typedValue) {
+				violations = append(violations, fmt.Sprintf("%s must match pattern '%s'", location, schema.Pattern))
+			}
+		}
+	case float64:
+		if schema.Minimum != nil && typedValue < *schema.Minimum {
+			violations = append(violations, fmt.Sprintf("%s must be greater than or equal to %v", location, *schema.Minimum))
+		}
+		if schema.Maximum != nil && typedValue > *schema.Maximum {
+			violations = append(violations, fmt.Sprintf("%s must be less than or equal to %v", location, *schema.Maximum))
+		}
+	}
+	return violations
+}
+
+func (document *openAPIDocument) validateObject(schema *openAPISchema, object map[string]any, location string) []string {
+	var violations []string
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			violations = append(violations, fmt.Sprintf("%s.%s is required", location, name))
+		}
+	}
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		propertyLocation := location + "." + name
+		if property, ok := schema.Properties[name]; ok {
+			violations = append(violations, document.validateValue(property, object[name], propertyLocation)...)
+			continue
+		}
+		if schema.NoAdditionalProps {
+			violations = append(violations, propertyLocation+" is not allowed")
+			continue
+		}
+		violations = append(violations, document.validateValue(schema.AdditionalProperties, object[name], propertyLocation)...)
+	}
+	return violations
+}
+
+func matchesSchemaType(schemaType openAPISchemaType, value any) bool {
+	switch typedValue := value.(type) {
+	case map[string]any:
+		return schemaType.has("object")
+	case []any:
+		return schemaType.has("array")
+	case string:
+		return schemaType.has("string")
+	case bool:
+		return schemaType.has("boolean")
+	case float64:
+		return schemaType.has("number") || (schemaType.has("integer") && typedValue == float64(int64(typedValue)))
+	}
+	return false
+}
+
+// enumContains reports whether the value is one of the enum values, comparing their JSON representations.
+func enumContains(enum []any, value any) bool {
+	actual, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range enum {
+		expected, err := json.Marshal(allowed)
+		if err == nil && string(expected) == string(actual) {
+			return true
+		}
+	}
+	return false
+}
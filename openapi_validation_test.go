@@ -0,0 +1,198 @@
+package mockhttp
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/le-yams/gotestingmock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OpenAPI_validation(t *testing.T) {
+	t.Parallel()
+
+	send := func(t *testing.T, mockedAPI *APIMock, method string, path string, body string) {
+		request, err := http.NewRequest(method, mockedAPI.GetURL().String()+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		if body != "" {
+			request.Header.Set("Content-Type", "application/json")
+		}
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		_ = response.Body.Close()
+	}
+
+	t.Run("passes when invocations comply with the contract", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithOpenAPIValidation([]byte(petstoreSpec)))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.
+			Stub(http.MethodGet, "/v1/pets/{petId}").
+			WithJSON(http.StatusOK, map[string]any{"id": 1, "name": "Rex", "tag": "dog"}).
+			Stub(http.MethodPost, "/v1/pets").
+			WithStatusCode(http.StatusCreated)
+
+		// Act
+		send(t, mockedAPI, http.MethodGet, "/v1/pets/1", "")
+		send(t, mockedAPI, http.MethodPost, "/v1/pets", `{"id": 2, "name": "Felix"}`)
+
+		// Assert
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("fails when the operation is not declared", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithOpenAPIValidation([]byte(petstoreSpec)))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodDelete, "/v1/pets/{petId}").WithStatusCode(http.StatusNoContent)
+
+		// Act
+		send(t, mockedAPI, http.MethodDelete, "/v1/pets/1", "")
+
+		// Assert
+		testState.AssertFailedWithErrorMessage("OpenAPI contract violation for request DELETE /v1/pets/1:\n" +
+			"- no operation declared for DELETE /v1/pets/1")
+	})
+
+	t.Run("ignores the invocations outside the server base path", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithOpenAPIValidation([]byte(petstoreSpec)))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/v10/pets").WithStatusCode(http.StatusNoContent)
+
+		// Act
+		send(t, mockedAPI, http.MethodGet, "/v10/pets", "")
+
+		// Assert
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("fails when a parameter does not match its schema", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithOpenAPIValidation([]byte(petstoreSpec)))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/v1/pets").WithJSON(http.StatusOK, []any{})
+
+		// Act
+		send(t, mockedAPI, http.MethodGet, "/v1/pets?limit=1000", "")
+
+		// Assert
+		testState.AssertFailedWithErrorMessage("OpenAPI contract violation for request GET /v1/pets:\n" +
+			"- query parameter 'limit' must be less than or equal to 100")
+	})
+
+	t.Run("fails when the request body does not match its schema", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithOpenAPIValidation([]byte(petstoreSpec)))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodPost, "/v1/pets").WithStatusCode(http.StatusCreated)
+
+		// Act
+		send(t, mockedAPI, http.MethodPost, "/v1/pets", `{"id": "two", "tag": "bird"}`)
+
+		// Assert
+		testState.AssertFailedWithErrorMessage("OpenAPI contract violation for request POST /v1/pets:\n" +
+			"- request body.name is required\n" +
+			"- request body.id must be of type integer\n" +
+			"- request body.tag must be one of [dog cat]")
+	})
+
+	t.Run("fails when the required request body is missing", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithOpenAPIValidation([]byte(petstoreSpec)))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodPost, "/v1/pets").WithStatusCode(http.StatusCreated)
+
+		// Act
+		send(t, mockedAPI, http.MethodPost, "/v1/pets", "")
+
+		// Assert
+		testState.AssertFailedWithErrorMessage("OpenAPI contract violation for request POST /v1/pets:\n" +
+			"- request body is required")
+	})
+
+	t.Run("fails when the stubbed response does not match its schema", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithOpenAPIValidation([]byte(petstoreSpec)))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/v1/pets/{petId}").WithJSON(http.StatusOK, map[string]any{"id": 1})
+
+		// Act
+		send(t, mockedAPI, http.MethodGet, "/v1/pets/1", "")
+
+		// Assert
+		testState.AssertFailedWithErrorMessage("OpenAPI contract violation for response of GET /v1/pets/1:\n" +
+			"- response body.name is required")
+	})
+
+	t.Run("fails when the stubbed response status is not declared", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithOpenAPIValidation([]byte(petstoreSpec)))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/v1/pets/{petId}").WithStatusCode(http.StatusTeapot)
+
+		// Act
+		send(t, mockedAPI, http.MethodGet, "/v1/pets/1", "")
+
+		// Assert
+		testState.AssertFailedWithErrorMessage("OpenAPI contract violation for response of GET /v1/pets/1:\n" +
+			"- response status 418 is not declared")
+	})
+
+	t.Run("matches the status code range written in lowercase", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		spec := `
+openapi: 3.0.0
+info:
+  title: health
+  version: "1"
+paths:
+  /health:
+    get:
+      responses:
+        2xx:
+          description: healthy
+`
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithOpenAPIValidation([]byte(spec)))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/health").WithStatusCode(http.StatusNoContent)
+
+		// Act
+		send(t, mockedAPI, http.MethodGet, "/health", "")
+
+		// Assert
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("fails when the spec cannot be read", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+
+		// Act
+		mockedAPI := API(testState, WithOpenAPIValidation([]byte("openapi: [")))
+		t.Cleanup(mockedAPI.Close)
+
+		// Assert
+		testState.AssertFailedWithFatal()
+	})
+}
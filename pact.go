@@ -0,0 +1,466 @@
+package mockhttp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// pactSpecificationVersion is the version of the Pact specification of the written contracts.
+const pactSpecificationVersion = "3.0.0"
+
+// pactFilesMu serializes the updates of contract files, several tests possibly contributing to the same contract.
+var pactFilesMu sync.Mutex
+
+// WithPactContract writes the invocations verified through a CallVerifier as the interactions of a Pact v3 contract
+// between the consumer and the provider. The contract file is written during test cleanup, unless the test failed.
+// When the file already exists, its interactions are merged with the new ones, those having the same description
+// being replaced.
+//
+// Each interaction request holds the method, the path, the query and the body of the invocation, along with the
+// headers asserted through Invocation.WithHeader (and its variants). Its response is the one served by the stub.
+// Sensitive data is redacted according to the configured redaction.
+func WithPactContract(consumer string, provider string, pactPath string) Option {
+	return func(mockedAPI *APIMock) {
+		mockedAPI.testState.Cleanup(func() {
+			if mockedAPI.testState.Failed() {
+				return
+			}
+			err := writePactContract(pactPath, consumer, provider, mockedAPI.pactInteractions())
+			if err != nil {
+				mockedAPI.testState.Error(err)
+			}
+		})
+	}
+}
+
+type pactContract struct {
+	Consumer     pactParticipant    `json:"consumer"`
+	Provider     pactParticipant    `json:"provider"`
+	Interactions []*pactInteraction `json:"interactions"`
+	Metadata     pactMetadata       `json:"metadata"`
+}
+
+type pactParticipant struct {
+	Name string `json:"name"`
+}
+
+type pactMetadata struct {
+	PactSpecification struct {
+		Version string `json:"version"`
+	} `json:"pactSpecification"`
+}
+
+type pactInteraction struct {
+	// Type is the type of a Pact v4 interaction, only "Synchronous/HTTP" interactions being supported.
+	Type           string              `json:"type,omitempty"`
+	Description    string              `json:"description"`
+	ProviderState  string              `json:"providerState,omitempty"`
+	ProviderStates []pactProviderState `json:"providerStates,omitempty"`
+	Request        pactRequest         `json:"request"`
+	Response       pactResponse        `json:"response"`
+}
+
+type pactProviderState struct {
+	Name string `json:"name"`
+}
+
+type pactRequest struct {
+	Method  string          `json:"method"`
+	Path    string          `json:"path"`
+	Query   pactQuery       `json:"query,omitempty"`
+	Headers pactHeaderMap   `json:"headers,omitempty"`
+	Body    json.RawMessage `json:"body,omitempty"`
+}
+
+type pactResponse struct {
+	Status  int             `json:"status"`
+	Headers pactHeaderMap   `json:"headers,omitempty"`
+	Body    json.RawMessage `json:"body,omitempty"`
+}
+
+// pactQuery is the query of an interaction request, encoded as a string by Pact v2 contracts.
+type pactQuery map[string][]string
+
+// UnmarshalJSON decodes a Pact v2 query string or a Pact v3/v4 map of values.
+func (query *pactQuery) UnmarshalJSON(data []byte) error {
+	var rawQuery string
+	if err := json.Unmarshal(data, &rawQuery); err == nil {
+		values, err := url.ParseQuery(rawQuery)
+		*query = pactQuery(values)
+		return err
+	}
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*query = pactQuery{}
+	for name, value := range raw {
+		values, err := stringOrStrings(value)
+		if err != nil {
+			return fmt.Errorf("invalid value of query parameter '%s'", name)
+		}
+		(*query)[name] = values
+	}
+	return nil
+}
+
+// pactHeaderMap are the headers of an interaction, multiple values being comma separated.
+type pactHeaderMap map[string]string
+
+// UnmarshalJSON decodes headers whose values are either a string or, in Pact v4 contracts, a list of strings.
+func (headers *pactHeaderMap) UnmarshalJSON(data []byte) error {
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*headers = pactHeaderMap{}
+	for name, value := range raw {
+		values, err := stringOrStrings(value)
+		if err != nil {
+			return fmt.Errorf("invalid value of header '%s'", name)
+		}
+		(*headers)[name] = strings.Join(values, ", ")
+	}
+	return nil
+}
+
+func stringOrStrings(data json.RawMessage) ([]string, error) {
+	var values []string
+	if err := json.Unmarshal(data, &values); err == nil {
+		return values, nil
+	}
+	var value string
+	err := json.Unmarshal(data, &value)
+	return []string{value}, err
+}
+
+// ignoredPactResponseHeaders are the response headers set by the server rather than by the stubs.
+var ignoredPactResponseHeaders = map[string]bool{
+	"Content-Length": true,
+	"Date":           true,
+}
+
+// pactInteractions returns the interactions of the verified invocations, in the order they were received.
+func (mockedAPI *APIMock) pactInteractions() []*pactInteraction {
+	mockedAPI.mu.Lock()
+	journal := append([]*Invocation{}, mockedAPI.journal...)
+	mockedAPI.mu.Unlock()
+
+	interactions := []*pactInteraction{}
+	descriptions := map[string]int{}
+	for _, invocation := range journal {
+		if !invocation.isVerified() || invocation.GetResponse() == nil {
+			continue
+		}
+		interaction := newPactInteraction(invocation, mockedAPI.redaction)
+		descriptions[interaction.Description]++
+		if count := descriptions[interaction.Description]; count > 1 {
+			interaction.Description = fmt.Sprintf("%s #%d", interaction.Description, count)
+		}
+		interactions = append(interactions, interaction)
+	}
+	return interactions
+}
+
+// newPactInteraction returns the interaction of the verified invocation, with its sensitive data redacted.
+func newPactInteraction(invocation *Invocation, redaction *Redaction) *pactInteraction {
+	request := invocation.GetRequest()
+	response := invocation.GetResponse()
+	interaction := &pactInteraction{
+		Description: request.Method + " " + redaction.requestURI(request.URL),
+		Request: pactRequest{
+			Method: request.Method,
+			Path:   request.URL.Path,
+			Body:   pactBody(redaction.body(invocation.GetPayload())),
+		},
+		Response: pactResponse{
+			Status: response.StatusCode,
+			Body:   pactBody(redaction.body(invocation.GetResponsePayload())),
+		},
+	}
+
+	if query := request.URL.Query(); len(query) > 0 {
+		interaction.Request.Query = pactQuery{}
+		for name, values := range query {
+			for _, value := range values {
+				interaction.Request.Query[name] = append(interaction.Request.Query[name], redaction.query(name, value))
+			}
+		}
+	}
+
+	headers := http.Header{}
+	for _, name := range invocation.getAssertedHeaders() {
+		headers[http.CanonicalHeaderKey(name)] = request.Header.Values(name)
+	}
+	if len(invocation.GetPayload()) > 0 && request.Header.Get("Content-Type") != "" {
+		headers["Content-Type"] = request.Header.Values("Content-Type")
+	}
+	interaction.Request.Headers = pactHeaders(redaction.headers(headers))
+
+	responseHeaders := http.Header{}
+	for name, values := range response.Header {
+		if !ignoredPactResponseHeaders[name] {
+			responseHeaders[name] = values
+		}
+	}
+	interaction.Response.Headers = pactHeaders(redaction.headers(responseHeaders))
+	return interaction
+}
+
+// pactHeaders returns the headers as expected by the Pact v3 specification, multiple values being comma separated.
+func pactHeaders(headers http.Header) pactHeaderMap {
+	if len(headers) == 0 {
+		return nil
+	}
+	pactHeaders := pactHeaderMap{}
+	for name, values := range headers {
+		pactHeaders[name] = strings.Join(values, ", ")
+	}
+	return pactHeaders
+}
+
+// pactBody returns the body as a JSON value, text bodies being JSON strings.
+func pactBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return body
+	}
+	text, _ := json.Marshal(string(body))
+	return text
+}
+
+// writePactContract writes the interactions to the contract file, merging them with its existing interactions.
+func writePactContract(pactPath string, consumer string, provider string, interactions []*pactInteraction) error {
+	pactFilesMu.Lock()
+	defer pactFilesMu.Unlock()
+
+	contract := &pactContract{
+		Consumer:     pactParticipant{Name: consumer},
+		Provider:     pactParticipant{Name: provider},
+		Interactions: []*pactInteraction{},
+	}
+	data, err := os.ReadFile(pactPath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		existing := &pactContract{}
+		if err := json.Unmarshal(data, existing); err != nil {
+			return fmt.Errorf("invalid Pact contract %s: %w", pactPath, err)
+		}
+		replaced := map[string]bool{}
+		for _, interaction := range interactions {
+			replaced[interaction.Description] = true
+		}
+		for _, interaction := range existing.Interactions {
+			if !replaced[interaction.Description] {
+				contract.Interactions = append(contract.Interactions, interaction)
+			}
+		}
+	}
+	contract.Interactions = append(contract.Interactions, interactions...)
+	contract.Metadata.PactSpecification.Version = pactSpecificationVersion
+
+	if err := os.MkdirAll(filepath.Dir(pactPath), 0o755); err != nil {
+		return err
+	}
+	data, err = json.MarshalIndent(contract, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(pactPath, data, 0o644)
+}
+
+// PactProviderStateScenario is the scenario whose state selects the provider state of the interactions stubbed by
+// FromPact, see APIMock.SetPactProviderState.
+const PactProviderStateScenario = "pact provider state"
+
+// FromPact creates a new APIMock instance stubbing the HTTP interactions of the Pact (v2, v3 or v4) contract file.
+// Invocations are matched on the method, path, query, headers and body of the interaction requests, JSON bodies being
+// compared semantically. Matching rules are not supported, the values of the contract must match exactly.
+//
+// An interaction having a provider state is only stubbed when the PactProviderStateScenario scenario is in that state
+// (see SetPactProviderState), the names of multiple provider states being joined with " and ". Such interactions take
+// precedence over the ones having no provider state. During test cleanup, the test fails for each interaction of the
+// contract which has not been exercised.
+func FromPact(testState T, pactPath string, options ...Option) *APIMock {
+	mockedAPI := API(testState, options...)
+	data, err := os.ReadFile(pactPath)
+	if err != nil {
+		testState.Fatal(err)
+		return mockedAPI
+	}
+	contract := &pactContract{}
+	if err := json.Unmarshal(data, contract); err != nil {
+		testState.Fatal(fmt.Errorf("invalid Pact contract %s: %w", pactPath, err))
+		return mockedAPI
+	}
+
+	v4 := strings.HasPrefix(contract.Metadata.PactSpecification.Version, "4")
+	exercised := make([]atomic.Bool, len(contract.Interactions))
+	// interactions having a provider state are registered last, taking precedence over the ones having none
+	for _, withProviderState := range []bool{false, true} {
+		for i, interaction := range contract.Interactions {
+			if !interaction.isHTTP() || interaction.hasProviderState() != withProviderState {
+				continue
+			}
+			if err := interaction.stub(mockedAPI, v4, &exercised[i]); err != nil {
+				testState.Fatal(fmt.Errorf("invalid Pact interaction '%s': %w", interaction.Description, err))
+				return mockedAPI
+			}
+		}
+	}
+
+	testState.Cleanup(func() {
+		for i, interaction := range contract.Interactions {
+			if interaction.isHTTP() && !exercised[i].Load() {
+				testState.Errorf("Pact interaction '%s' has not been exercised", interaction.Description)
+			}
+		}
+	})
+	return mockedAPI
+}
+
+// SetPactProviderState selects the provider state of the interactions stubbed by FromPact.
+func (mockedAPI *APIMock) SetPactProviderState(state string) *APIMock {
+	return mockedAPI.SetScenarioState(PactProviderStateScenario, state)
+}
+
+func (interaction *pactInteraction) isHTTP() bool {
+	return interaction.Type == "" || interaction.Type == "Synchronous/HTTP"
+}
+
+func (interaction *pactInteraction) hasProviderState() bool {
+	return interaction.ProviderState != "" || len(interaction.ProviderStates) > 0
+}
+
+// stub registers the interaction as a stub of the mocked API, marking it as exercised once called.
+func (interaction *pactInteraction) stub(mockedAPI *APIMock, v4 bool, exercised *atomic.Bool) error {
+	request := interaction.Request
+	builder := mockedAPI.Stub(request.Method, request.Path)
+
+	expectedQuery := url.Values(request.Query)
+	builder.Matching(func(actual *http.Request, payload []byte) bool {
+		return matchesRecordedQuery(expectedQuery, actual.URL.Query())
+	})
+	for name, expected := range request.Headers {
+		builder.Matching(func(actual *http.Request, payload []byte) bool {
+			return equalPactHeaderValues(expected, strings.Join(actual.Header.Values(name), ","))
+		})
+	}
+	expectedBody, err := decodePactBody(request.Body, pactContentType(request.Headers), v4)
+	if err != nil {
+		return err
+	}
+	if expectedBody != nil {
+		builder.Matching(func(actual *http.Request, payload []byte) bool {
+			return equalBodies(expectedBody, payload)
+		})
+	}
+
+	states := []string{}
+	if interaction.ProviderState != "" {
+		states = append(states, interaction.ProviderState)
+	}
+	for _, state := range interaction.ProviderStates {
+		states = append(states, state.Name)
+	}
+	if len(states) > 0 {
+		builder.InScenario(PactProviderStateScenario, strings.Join(states, " and "), "")
+	}
+
+	response := &stubResponse{
+		statusCode: interaction.Response.Status,
+		header:     http.Header{},
+	}
+	if response.statusCode == 0 {
+		response.statusCode = http.StatusOK
+	}
+	for name, value := range interaction.Response.Headers {
+		response.header.Set(name, value)
+	}
+	response.body, err = decodePactBody(interaction.Response.Body, pactContentType(interaction.Response.Headers), v4)
+	if err != nil {
+		return err
+	}
+	builder.register(func(writer http.ResponseWriter, request *http.Request) {
+		exercised.Store(true)
+		response.handler(writer, request)
+	}, response)
+	return nil
+}
+
+// pactContentType returns the value of the Content-Type header, whatever the case of its name.
+func pactContentType(headers pactHeaderMap) string {
+	for name, value := range headers {
+		if strings.EqualFold(name, "Content-Type") {
+			return value
+		}
+	}
+	return ""
+}
+
+// equalPactHeaderValues compares comma separated header values, ignoring the spaces around each value.
+func equalPactHeaderValues(expected string, actual string) bool {
+	expectedValues := strings.Split(expected, ",")
+	actualValues := strings.Split(actual, ",")
+	if len(expectedValues) != len(actualValues) {
+		return false
+	}
+	for i := range expectedValues {
+		if strings.TrimSpace(expectedValues[i]) != strings.TrimSpace(actualValues[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// pactV4Body is the body of a Pact v4 interaction.
+type pactV4Body struct {
+	Content     json.RawMessage `json:"content"`
+	ContentType string          `json:"contentType"`
+	Encoded     any             `json:"encoded"`
+}
+
+// decodePactBody returns the bytes of the interaction body, nil when there is none. JSON strings are decoded as text
+// unless the content type is JSON.
+func decodePactBody(body json.RawMessage, contentType string, v4 bool) ([]byte, error) {
+	if len(body) == 0 || string(body) == "null" {
+		return nil, nil
+	}
+	if v4 {
+		v4Body := &pactV4Body{}
+		if err := json.Unmarshal(body, v4Body); err != nil {
+			return nil, err
+		}
+		if v4Body.ContentType != "" {
+			contentType = v4Body.ContentType
+		}
+		if encoding, ok := v4Body.Encoded.(string); ok && strings.EqualFold(encoding, "base64") {
+			var encoded string
+			if err := json.Unmarshal(v4Body.Content, &encoded); err != nil {
+				return nil, err
+			}
+			return base64.StdEncoding.DecodeString(encoded)
+		}
+		body = v4Body.Content
+	}
+	var text string
+	if !isJSONContentType(contentType) && json.Unmarshal(body, &text) == nil {
+		return []byte(text), nil
+	}
+	return body, nil
+}
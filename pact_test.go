@@ -0,0 +1,330 @@
+package mockhttp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithPactContract(t *testing.T) {
+	t.Parallel()
+
+	readContract := func(t *testing.T, pactPath string) *pactContract {
+		data, err := os.ReadFile(pactPath)
+		require.NoError(t, err)
+		contract := &pactContract{}
+		require.NoError(t, json.Unmarshal(data, contract))
+		return contract
+	}
+
+	t.Run("should write the verified invocations as interactions", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		pactPath := filepath.Join(t.TempDir(), "pacts", "web-users.json")
+		mockedAPI := API(testState, WithPactContract("web", "users", pactPath), WithRedaction(Redaction{Headers: []string{"Authorization"}}))
+		mockedAPI.
+			Stub(http.MethodPost, "/users").WithJSON(http.StatusCreated, map[string]any{"id": 42}).
+			Stub(http.MethodGet, "/health").WithStatusCode(http.StatusOK)
+
+		request, err := http.NewRequest(http.MethodPost, mockedAPI.GetURL().String()+"/users?notify=true", strings.NewReader(`{"name":"John"}`))
+		require.NoError(t, err)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Authorization", "Bearer secret")
+		request.Header.Set("X-Request-Id", "123")
+		_, err = http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		_, err = http.Get(mockedAPI.GetURL().String() + "/health")
+		require.NoError(t, err)
+
+		mockedAPI.Verify(http.MethodPost, "/users").HasBeenCalledOnce().
+			WithBearerAuthHeader("secret").
+			WithJSONPayload(map[string]any{"name": "John"})
+
+		// Act
+		runCleanups(testState)
+
+		// Assert
+		testState.AssertDidNotFailed()
+		contract := readContract(t, pactPath)
+		assert := assertions.New(t)
+		assert.Equal("web", contract.Consumer.Name)
+		assert.Equal("users", contract.Provider.Name)
+		assert.Equal("3.0.0", contract.Metadata.PactSpecification.Version)
+		require.Len(t, contract.Interactions, 1)
+		interaction := contract.Interactions[0]
+		assert.Equal("POST /users?notify=true", interaction.Description)
+		assert.Equal(http.MethodPost, interaction.Request.Method)
+		assert.Equal("/users", interaction.Request.Path)
+		assert.Equal(pactQuery{"notify": {"true"}}, interaction.Request.Query)
+		assert.Equal(pactHeaderMap{
+			"Authorization": "Bearer [REDACTED]",
+			"Content-Type":  "application/json",
+		}, interaction.Request.Headers)
+		assert.JSONEq(`{"name": "John"}`, string(interaction.Request.Body))
+		assert.Equal(http.StatusCreated, interaction.Response.Status)
+		assert.Equal(pactHeaderMap{"Content-Type": "application/json"}, interaction.Response.Headers)
+		assert.JSONEq(`{"id": 42}`, string(interaction.Response.Body))
+	})
+
+	t.Run("should redact the query of the interaction descriptions", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		pactPath := filepath.Join(t.TempDir(), "web-users.json")
+		mockedAPI := API(testState, WithPactContract("web", "users", pactPath), WithRedaction(Redaction{QueryParams: []string{"api_key"}}))
+		mockedAPI.Stub(http.MethodGet, "/users").WithJSON(http.StatusOK, []any{})
+		_, err := http.Get(mockedAPI.GetURL().String() + "/users?api_key=secret")
+		require.NoError(t, err)
+		mockedAPI.Verify(http.MethodGet, "/users").HasBeenCalledOnce()
+
+		// Act
+		runCleanups(testState)
+
+		// Assert
+		testState.AssertDidNotFailed()
+		contract := readContract(t, pactPath)
+		require.Len(t, contract.Interactions, 1)
+		interaction := contract.Interactions[0]
+		assert := assertions.New(t)
+		assert.Equal("GET /users?api_key=%5BREDACTED%5D", interaction.Description)
+		assert.Equal(pactQuery{"api_key": {RedactedValue}}, interaction.Request.Query)
+		data, err := os.ReadFile(pactPath)
+		require.NoError(t, err)
+		assert.NotContains(string(data), "secret")
+	})
+
+	t.Run("should merge the interactions with the existing contract", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		pactPath := filepath.Join(t.TempDir(), "web-users.json")
+		for _, path := range []string{"/users/1", "/users/2"} {
+			testState := testingmock.New(t)
+			mockedAPI := API(testState, WithPactContract("web", "users", pactPath))
+			mockedAPI.Stub(http.MethodGet, "/users/{id}").WithBody(http.StatusOK, []byte("user"), "text/plain")
+			_, err := http.Get(mockedAPI.GetURL().String() + path)
+			require.NoError(t, err)
+			mockedAPI.Verify(http.MethodGet, path).HasBeenCalledOnce()
+
+			// Act
+			runCleanups(testState)
+		}
+
+		// Assert
+		contract := readContract(t, pactPath)
+		require.Len(t, contract.Interactions, 2)
+		assert := assertions.New(t)
+		assert.Equal("GET /users/1", contract.Interactions[0].Description)
+		assert.Equal("GET /users/2", contract.Interactions[1].Description)
+		assert.JSONEq(`"user"`, string(contract.Interactions[1].Response.Body))
+	})
+
+	t.Run("should not write the contract when the test failed", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		pactPath := filepath.Join(t.TempDir(), "web-users.json")
+		mockedAPI := API(testState, WithPactContract("web", "users", pactPath))
+		mockedAPI.Verify(http.MethodGet, "/users").HasBeenCalledOnce()
+
+		// Act
+		runCleanups(testState)
+
+		// Assert
+		assertions.New(t).NoFileExists(pactPath)
+	})
+}
+
+const usersPact = `{
+  "consumer": {"name": "web"},
+  "provider": {"name": "users"},
+  "interactions": [
+    {
+      "description": "a request for user 42",
+      "providerStates": [{"name": "user 42 exists"}],
+      "request": {"method": "GET", "path": "/users/42", "headers": {"Accept": "application/json"}},
+      "response": {"status": 200, "headers": {"Content-Type": "application/json"}, "body": {"id": 42, "name": "John"}}
+    },
+    {
+      "description": "a request for a missing user 42",
+      "request": {"method": "GET", "path": "/users/42", "headers": {"Accept": "application/json"}},
+      "response": {"status": 404}
+    },
+    {
+      "description": "a request to create a user",
+      "request": {
+        "method": "POST",
+        "path": "/users",
+        "query": {"notify": ["true"]},
+        "headers": {"Content-Type": "application/json"},
+        "body": {"name": "John"}
+      },
+      "response": {"status": 201, "body": "created"}
+    }
+  ],
+  "metadata": {"pactSpecification": {"version": "3.0.0"}}
+}`
+
+func Test_FromPact(t *testing.T) {
+	t.Parallel()
+
+	writePact := func(t *testing.T, content string) string {
+		pactPath := filepath.Join(t.TempDir(), "pact.json")
+		require.NoError(t, os.WriteFile(pactPath, []byte(content), 0o600))
+		return pactPath
+	}
+
+	send := func(t *testing.T, mockedAPI *APIMock, method string, path string, body string) (int, string) {
+		request, err := http.NewRequest(method, mockedAPI.GetURL().String()+path, strings.NewReader(body))
+		require.NoError(t, err)
+		request.Header.Set("Accept", "application/json")
+		if body != "" {
+			request.Header.Set("Content-Type", "application/json")
+		}
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		responseBody, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response.StatusCode, string(responseBody)
+	}
+
+	t.Run("should stub the interactions", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := FromPact(testState, writePact(t, usersPact))
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		missingStatus, _ := send(t, mockedAPI, http.MethodGet, "/users/42", "")
+		mockedAPI.SetPactProviderState("user 42 exists")
+		existingStatus, existingBody := send(t, mockedAPI, http.MethodGet, "/users/42", "")
+		createdStatus, createdBody := send(t, mockedAPI, http.MethodPost, "/users?notify=true", `{"name": "John"}`)
+
+		// Assert
+		assert := assertions.New(t)
+		assert.Equal(http.StatusNotFound, missingStatus)
+		assert.Equal(http.StatusOK, existingStatus)
+		assert.JSONEq(`{"id": 42, "name": "John"}`, existingBody)
+		assert.Equal(http.StatusCreated, createdStatus)
+		assert.Equal("created", createdBody)
+		runCleanups(testState)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should not stub requests not matching the interactions", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := FromPact(testState, writePact(t, usersPact))
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		status, _ := send(t, mockedAPI, http.MethodPost, "/users", `{"name": "John"}`)
+
+		// Assert
+		assertions.New(t).Equal(http.StatusNotFound, status)
+		testState.AssertFailedWithFatal()
+	})
+
+	t.Run("should fail when an interaction has not been exercised", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := FromPact(testState, writePact(t, usersPact))
+		t.Cleanup(mockedAPI.Close)
+		send(t, mockedAPI, http.MethodGet, "/users/42", "")
+		send(t, mockedAPI, http.MethodPost, "/users?notify=true", `{"name": "John"}`)
+
+		// Act
+		runCleanups(testState)
+
+		// Assert
+		testState.AssertFailedWithErrorMessage("Pact interaction 'a request for user 42' has not been exercised")
+	})
+
+	t.Run("should support Pact v4 contracts", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		pactPath := writePact(t, `{
+			"interactions": [
+				{
+					"type": "Synchronous/HTTP",
+					"description": "a request for the logo",
+					"request": {"method": "GET", "path": "/logo", "query": {"size": ["small"]}, "headers": {"Accept": ["application/json"]}},
+					"response": {"status": 200, "body": {"content": "iVBORw0K", "contentType": "image/png", "encoded": "base64"}}
+				},
+				{"type": "Asynchronous/Messages", "description": "a user created event"}
+			],
+			"metadata": {"pactSpecification": {"version": "4.0"}}
+		}`)
+		mockedAPI := FromPact(testState, pactPath)
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		status, body := send(t, mockedAPI, http.MethodGet, "/logo?size=small", "")
+
+		// Assert
+		assert := assertions.New(t)
+		assert.Equal(http.StatusOK, status)
+		assert.Equal("\x89PNG\r\n", body)
+		runCleanups(testState)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should stub the contracts written with WithPactContract", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		pactPath := filepath.Join(t.TempDir(), "pact.json")
+		consumerState := testingmock.New(t)
+		consumerAPI := API(consumerState, WithPactContract("web", "users", pactPath))
+		consumerAPI.Stub(http.MethodPost, "/users").WithJSON(http.StatusCreated, map[string]any{"id": 42})
+		send(t, consumerAPI, http.MethodPost, "/users?notify=true", `{"name": "John"}`)
+		consumerAPI.Verify(http.MethodPost, "/users").HasBeenCalledOnce().WithHeader("Accept", "application/json")
+		runCleanups(consumerState)
+
+		testState := testingmock.New(t)
+		mockedAPI := FromPact(testState, pactPath)
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		status, body := send(t, mockedAPI, http.MethodPost, "/users?notify=true", `{"name": "John"}`)
+
+		// Assert
+		assert := assertions.New(t)
+		assert.Equal(http.StatusCreated, status)
+		assert.JSONEq(`{"id": 42}`, body)
+		runCleanups(testState)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should fail when the contract does not exist", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+
+		// Act
+		mockedAPI := FromPact(testState, filepath.Join(t.TempDir(), "missing.json"))
+		t.Cleanup(mockedAPI.Close)
+
+		// Assert
+		testState.AssertFailedWithFatal()
+	})
+}
+
+func runCleanups(testState *testingmock.MockedT) {
+	cleanups := testState.GetCleanups()
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
+	}
+}
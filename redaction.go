@@ -0,0 +1,201 @@
+package mockhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RedactedValue replaces the sensitive data in failure messages, exported files and cassettes.
+const RedactedValue = "[REDACTED]"
+
+// Redaction describes the sensitive data of the invocations which must never be printed nor written.
+type Redaction struct {
+	// Headers are the names (case-insensitive) of the headers whose values are redacted. The scheme of
+	// an Authorization header value is kept, e.g. "Bearer [REDACTED]".
+	Headers []string
+	// QueryParams are the names of the query parameters whose values are redacted.
+	QueryParams []string
+	// JSONPaths are the dot separated paths of the JSON body values to redact, e.g. "$.user.password".
+	// A "*" segment matches any object key or array index, e.g. "$.accounts.*.iban".
+	JSONPaths []string
+	// Patterns are the regular expressions whose matches are redacted in header values, query values and bodies.
+	Patterns []*regexp.Regexp
+}
+
+// WithRedaction masks the given sensitive data whenever invocations are printed in failure messages, exported to
+// files or written to cassettes.
+func WithRedaction(redaction Redaction) Option {
+	return func(mockedAPI *APIMock) {
+		mockedAPI.redaction = &redaction
+	}
+}
+
+// header returns the redacted value of the named header.
+func (redaction *Redaction) header(name string, value string) string {
+	if redaction == nil {
+		return value
+	}
+	for _, sensitive := range redaction.Headers {
+		if strings.EqualFold(sensitive, name) {
+			if scheme, _, found := strings.Cut(value, " "); found && strings.EqualFold(name, "Authorization") {
+				return scheme + " " + RedactedValue
+			}
+			return RedactedValue
+		}
+	}
+	return redaction.text(value)
+}
+
+// headerValues returns the redacted values of the named header.
+func (redaction *Redaction) headerValues(name string, values []string) []string {
+	if redaction == nil || values == nil {
+		return values
+	}
+	redacted := make([]string, 0, len(values))
+	for _, value := range values {
+		redacted = append(redacted, redaction.header(name, value))
+	}
+	return redacted
+}
+
+// headers returns a copy of the headers with their values redacted.
+func (redaction *Redaction) headers(headers http.Header) http.Header {
+	if redaction == nil || headers == nil {
+		return headers.Clone()
+	}
+	redacted := http.Header{}
+	for name, values := range headers {
+		redacted[name] = redaction.headerValues(name, values)
+	}
+	return redacted
+}
+
+// query returns the redacted value of the named query parameter.
+func (redaction *Redaction) query(name string, value string) string {
+	if redaction == nil {
+		return value
+	}
+	for _, sensitive := range redaction.QueryParams {
+		if sensitive == name {
+			return RedactedValue
+		}
+	}
+	return redaction.text(value)
+}
+
+// rawQuery returns the encoded query with its values redacted.
+func (redaction *Redaction) rawQuery(rawQuery string) string {
+	if redaction == nil || rawQuery == "" {
+		return rawQuery
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return redaction.text(rawQuery)
+	}
+	for name, parameterValues := range values {
+		for i, value := range parameterValues {
+			parameterValues[i] = redaction.query(name, value)
+		}
+	}
+	return values.Encode()
+}
+
+// requestURI returns the escaped path and query of the URL, the query values being redacted.
+func (redaction *Redaction) requestURI(requestURL *url.URL) string {
+	rawQuery := redaction.rawQuery(requestURL.RawQuery)
+	if rawQuery == "" {
+		return requestURL.EscapedPath()
+	}
+	return requestURL.EscapedPath() + "?" + rawQuery
+}
+
+// body returns the body with the configured JSON paths and patterns redacted.
+func (redaction *Redaction) body(body []byte) []byte {
+	if redaction == nil || len(body) == 0 {
+		return body
+	}
+	if len(redaction.JSONPaths) > 0 {
+		body = redaction.jsonBody(body)
+	}
+	return []byte(redaction.text(string(body)))
+}
+
+// jsonBody returns the JSON body with the configured JSON paths redacted, the body being left untouched when no path
+// matches. Numbers are kept as is and HTML characters are not escaped.
+func (redaction *Redaction) jsonBody(body []byte) []byte {
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return body
+	}
+	redacted := false
+	for _, path := range redaction.JSONPaths {
+		var matched bool
+		value, matched = redactJSONPath(value, splitJSONPath(path))
+		redacted = redacted || matched
+	}
+	if !redacted {
+		return body
+	}
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return body
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n"))
+}
+
+// text returns the text with the matches of the configured patterns redacted.
+func (redaction *Redaction) text(text string) string {
+	if redaction == nil {
+		return text
+	}
+	for _, pattern := range redaction.Patterns {
+		text = pattern.ReplaceAllLiteralString(text, RedactedValue)
+	}
+	return text
+}
+
+func splitJSONPath(path string) []string {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// redactJSONPath replaces the values found at the given path segments of the decoded JSON value, and returns whether
+// a value has been found.
+func redactJSONPath(value any, segments []string) (any, bool) {
+	if len(segments) == 0 {
+		return RedactedValue, true
+	}
+	segment, rest := segments[0], segments[1:]
+	redacted := false
+	switch typedValue := value.(type) {
+	case map[string]any:
+		for key, item := range typedValue {
+			if segment == "*" || segment == key {
+				var matched bool
+				typedValue[key], matched = redactJSONPath(item, rest)
+				redacted = redacted || matched
+			}
+		}
+	case []any:
+		for i, item := range typedValue {
+			if segment == "*" || segment == strconv.Itoa(i) {
+				var matched bool
+				typedValue[i], matched = redactJSONPath(item, rest)
+				redacted = redacted || matched
+			}
+		}
+	}
+	return value, redacted
+}
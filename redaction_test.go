@@ -0,0 +1,164 @@
+package mockhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Redaction(t *testing.T) {
+	t.Parallel()
+
+	newRedactedInvocation := func(t *testing.T, testState T, redaction Redaction, body string) *Invocation {
+		request, err := http.NewRequest(http.MethodPost, "/login?api_key=secret-key&page=1", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		request.Header.Set("Authorization", "Bearer secret-token")
+		invocation := newInvocation(request, testState)
+		invocation.redaction = &redaction
+		return invocation
+	}
+
+	t.Run("masks redacted headers in failure messages", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		invocation := newRedactedInvocation(t, testState, Redaction{Headers: []string{"authorization"}}, "")
+
+		// Act
+		invocation.WithBearerAuthHeader("expected-token")
+
+		// Assert
+		testState.AssertFailedWithErrorMessage("Not equal:\nexpected: [Bearer [REDACTED]]\nactual  : [Bearer [REDACTED]]")
+	})
+
+	t.Run("does not fail when redacted values match", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		invocation := newRedactedInvocation(t, testState, Redaction{
+			Headers:     []string{"Authorization"},
+			QueryParams: []string{"api_key"},
+		}, "")
+
+		// Act
+		invocation.
+			WithBearerAuthHeader("secret-token").
+			WithQueryValue("api_key", "secret-key")
+
+		// Assert
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("masks redacted query parameters in failure messages", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		invocation := newRedactedInvocation(t, testState, Redaction{QueryParams: []string{"api_key"}}, "")
+
+		// Act
+		invocation.WithQueryValue("api_key", "other-key")
+
+		// Assert
+		testState.AssertFailedWithErrorMessage("Not equal:\nexpected: [REDACTED]\nactual  : [REDACTED]")
+	})
+
+	t.Run("masks JSON paths and patterns of payloads in failure messages", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		invocation := newRedactedInvocation(t, testState, Redaction{
+			JSONPaths: []string{"$.password"},
+			Patterns:  []*regexp.Regexp{regexp.MustCompile(`\d{4}-\d{4}`)},
+		}, `{"user": "john", "password": "secret", "card": "1234-5678"}`)
+
+		// Act
+		invocation.WithJSONPayload(map[string]any{"user": "jane", "password": "other", "card": "0000-0000"})
+
+		// Assert
+		testState.AssertFailedWithErrorMessage("Not equal:\n" +
+			`expected: {"card":"[REDACTED]","password":"[REDACTED]","user":"jane"}` + "\n" +
+			`actual  : {"card":"[REDACTED]","password":"[REDACTED]","user":"john"}`)
+	})
+
+	t.Run("keeps the JSON bodies without redacted path untouched", func(t *testing.T) {
+		t.Parallel()
+		redaction := &Redaction{JSONPaths: []string{"$.password"}}
+		body := `{"id": 9007199254740993, "html": "<b>&</b>"}`
+
+		assertions.Equal(t, body, string(redaction.body([]byte(body))))
+	})
+
+	t.Run("keeps the numbers and HTML characters of redacted JSON bodies", func(t *testing.T) {
+		t.Parallel()
+		redaction := &Redaction{JSONPaths: []string{"$.password"}}
+		body := `{"id": 9007199254740993, "html": "<b>&</b>", "password": "secret"}`
+
+		assertions.Equal(t, `{"html":"<b>&</b>","id":9007199254740993,"password":"[REDACTED]"}`,
+			string(redaction.body([]byte(body))))
+	})
+
+	t.Run("masks redacted form values in failure messages", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		invocation := newRedactedInvocation(t, testState, Redaction{QueryParams: []string{"password"}}, "password=secret")
+		invocation.GetRequest().Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		// Act
+		invocation.WithUrlEncodedFormPayload().WithValue("password", "other")
+
+		// Assert
+		testState.AssertFailedWithErrorMessage("Not equal:\nexpected: [REDACTED]\nactual  : [REDACTED]")
+	})
+
+	t.Run("redacts recorded cassettes", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		upstream := API(testingmock.New(t))
+		t.Cleanup(upstream.Close)
+		upstream.Stub(http.MethodPost, "/login").WithJSON(http.StatusOK, map[string]any{"token": "secret-token"})
+
+		cassettePath := filepath.Join(t.TempDir(), "cassette.json")
+		testState := testingmock.New(t)
+		mockedAPI := Record(testState, upstream.GetURL().String(), cassettePath, WithRedaction(Redaction{
+			Headers:     []string{"Authorization"},
+			QueryParams: []string{"api_key"},
+			JSONPaths:   []string{"token", "password"},
+		}))
+
+		request, err := http.NewRequest(http.MethodPost, mockedAPI.GetURL().String()+"/login?api_key=secret-key",
+			bytes.NewBufferString(`{"password": "secret"}`))
+		require.NoError(t, err)
+		request.Header.Set("Authorization", "Basic c2VjcmV0")
+		_, err = http.DefaultClient.Do(request)
+		require.NoError(t, err)
+
+		// Act
+		for _, cleanup := range testState.GetCleanups() {
+			cleanup()
+		}
+
+		// Assert
+		data, err := os.ReadFile(cassettePath)
+		require.NoError(t, err)
+		recorded := cassette{}
+		require.NoError(t, json.Unmarshal(data, &recorded))
+		require.Len(t, recorded.Interactions, 1)
+
+		assert := assertions.New(t)
+		interaction := recorded.Interactions[0]
+		assert.Equal("Basic [REDACTED]", interaction.Request.Headers.Get("Authorization"))
+		assert.Equal("api_key=%5BREDACTED%5D", interaction.Request.Query)
+		assert.JSONEq(`{"password": "[REDACTED]"}`, interaction.Request.Body)
+		assert.JSONEq(`{"token": "[REDACTED]"}`, interaction.Response.Body)
+		assert.NotContains(string(data), "secret")
+	})
+}
@@ -0,0 +1,208 @@
+package mockhttp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSEEvent is a server-sent event of a stub created with StubBuilder.WithSSE or StubBuilder.WithSSEStream.
+type SSEEvent struct {
+	// Event is the event type, "message" for clients when empty.
+	Event string
+	// ID is the event id, sent back by reconnecting clients in the Last-Event-ID header.
+	ID string
+	// Data is the event data, sent as one data line per line.
+	Data string
+	// Retry is the reconnection time sent to the client, not sent when zero.
+	Retry time.Duration
+	// Comment is sent as a comment line, ignored by clients. An event with a comment only is a keep-alive.
+	Comment string
+	// Delay is waited before sending the event.
+	Delay time.Duration
+
+	disconnect bool
+}
+
+// SSEDisconnect returns the event abruptly closing the connection, without ending the response, once the given delay
+// has elapsed. It simulates a network failure or a server crash in the middle of the stream.
+func SSEDisconnect(delay time.Duration) SSEEvent {
+	return SSEEvent{Delay: delay, disconnect: true}
+}
+
+// WithSSE creates a new stub handler streaming the given server-sent events, each one being flushed once sent, then
+// ending the response:
+//
+//	mockedAPI.Stub(http.MethodGet, "/notifications").WithSSE(
+//		mockhttp.SSEEvent{Event: "created", ID: "1", Data: `{"id": 1}`},
+//		mockhttp.SSEEvent{Comment: "keep-alive", Delay: time.Second},
+//		mockhttp.SSEEvent{Event: "deleted", ID: "2", Data: `{"id": 1}`},
+//	)
+func (stub *StubBuilder) WithSSE(events ...SSEEvent) *APIMock {
+	return stub.register(func(writer http.ResponseWriter, request *http.Request) {
+		startSSE(writer)
+		for _, event := range events {
+			if !writeSSEEvent(writer, request, event) {
+				return
+			}
+		}
+	}, nil)
+}
+
+// WithSSEStream creates a new stub handler streaming the server-sent events pushed by the test with SSEStream.Send,
+// the connections being kept open until SSEStream.Close is called, the clients disconnect or the test ends.
+func (stub *StubBuilder) WithSSEStream() *SSEStream {
+	stream := &SSEStream{
+		connections: map[*sseConnection]struct{}{},
+		closed:      make(chan struct{}),
+	}
+	stub.register(stream.handler, nil)
+	stub.api.testState.Cleanup(stream.Close)
+	return stream
+}
+
+// SSEStream pushes server-sent events to the clients connected to a stub, see StubBuilder.WithSSEStream.
+// The events sent while no client is connected are sent to the next client connecting.
+type SSEStream struct {
+	connections map[*sseConnection]struct{}
+	pending     []SSEEvent
+	closed      chan struct{}
+	closeOnce   sync.Once
+	mu          sync.Mutex
+}
+
+// sseConnection is a client connected to an SSE stream, along with the events to send to it.
+type sseConnection struct {
+	events []SSEEvent
+	notify chan struct{}
+}
+
+// Send pushes the events to the connected clients, or to the next client connecting when there is none.
+func (stream *SSEStream) Send(events ...SSEEvent) *SSEStream {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	if len(stream.connections) == 0 {
+		stream.pending = append(stream.pending, events...)
+		return stream
+	}
+	for connection := range stream.connections {
+		connection.events = append(connection.events, events...)
+		connection.wake()
+	}
+	return stream
+}
+
+// Close ends the responses of the connected clients once their events have been sent, the next clients getting an
+// empty stream.
+func (stream *SSEStream) Close() {
+	stream.closeOnce.Do(func() {
+		close(stream.closed)
+	})
+}
+
+// Connections returns the number of clients currently connected.
+func (stream *SSEStream) Connections() int {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	return len(stream.connections)
+}
+
+func (stream *SSEStream) handler(writer http.ResponseWriter, request *http.Request) {
+	connection := &sseConnection{notify: make(chan struct{}, 1)}
+	stream.mu.Lock()
+	connection.events, stream.pending = stream.pending, nil
+	stream.connections[connection] = struct{}{}
+	stream.mu.Unlock()
+	defer func() {
+		stream.mu.Lock()
+		delete(stream.connections, connection)
+		stream.mu.Unlock()
+	}()
+
+	startSSE(writer)
+	for {
+		for event, ok := stream.next(connection); ok; event, ok = stream.next(connection) {
+			if !writeSSEEvent(writer, request, event) {
+				return
+			}
+		}
+		select {
+		case <-connection.notify:
+		case <-stream.closed:
+			if !stream.hasNext(connection) {
+				return
+			}
+		case <-request.Context().Done():
+			return
+		}
+	}
+}
+
+// next removes and returns the next event to send to the connection, if any.
+func (stream *SSEStream) next(connection *sseConnection) (SSEEvent, bool) {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	if len(connection.events) == 0 {
+		return SSEEvent{}, false
+	}
+	event := connection.events[0]
+	connection.events = connection.events[1:]
+	return event, true
+}
+
+func (stream *SSEStream) hasNext(connection *sseConnection) bool {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	return len(connection.events) > 0
+}
+
+func (connection *sseConnection) wake() {
+	select {
+	case connection.notify <- struct{}{}:
+	default:
+	}
+}
+
+// startSSE sends the header of an SSE response.
+func startSSE(writer http.ResponseWriter) {
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.WriteHeader(http.StatusOK)
+	_ = http.NewResponseController(writer).Flush()
+}
+
+// writeSSEEvent waits for the event delay then sends the event, returning false when the client has disconnected.
+// A disconnect event aborts the handler.
+func writeSSEEvent(writer http.ResponseWriter, request *http.Request, event SSEEvent) bool {
+	if !wait(request, event.Delay) {
+		return false
+	}
+	if event.disconnect {
+		panic(http.ErrAbortHandler)
+	}
+
+	text := &strings.Builder{}
+	if event.Comment != "" {
+		for _, line := range strings.Split(event.Comment, "\n") {
+			_, _ = fmt.Fprintf(text, ": %s\n", line)
+		}
+	}
+	if event.ID != "" {
+		_, _ = fmt.Fprintf(text, "id: %s\n", event.ID)
+	}
+	if event.Event != "" {
+		_, _ = fmt.Fprintf(text, "event: %s\n", event.Event)
+	}
+	if event.Retry > 0 {
+		_, _ = fmt.Fprintf(text, "retry: %d\n", event.Retry.Milliseconds())
+	}
+	if event.Data != "" || event.Event != "" || event.ID != "" {
+		for _, line := range strings.Split(event.Data, "\n") {
+			_, _ = fmt.Fprintf(text, "data: %s\n", line)
+		}
+	}
+	text.WriteString("\n")
+	return writeChunk(writer, []byte(text.String()))
+}
@@ -0,0 +1,159 @@
+package mockhttp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SSE(t *testing.T) {
+	t.Parallel()
+
+	t.Run("WithSSE() should stream the events", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/notifications").WithSSE(
+			SSEEvent{Retry: 3 * time.Second},
+			SSEEvent{Event: "created", ID: "1", Data: "{\n\"id\": 1\n}"},
+			SSEEvent{Comment: "keep-alive", Delay: 10 * time.Millisecond},
+			SSEEvent{Data: "done"},
+		)
+
+		// Act
+		response, err := http.Get(mockedAPI.GetURL().String() + "/notifications")
+
+		// Assert
+		require.NoError(t, err)
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		assert := assertions.New(t)
+		assert.Equal("text/event-stream", response.Header.Get("Content-Type"))
+		assert.Equal("retry: 3000\n\n"+
+			"id: 1\nevent: created\ndata: {\ndata: \"id\": 1\ndata: }\n\n"+
+			": keep-alive\n\n"+
+			"data: done\n\n", string(body))
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("WithSSE() should flush each event", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/notifications").WithSSE(
+			SSEEvent{Data: "first"},
+			SSEEvent{Data: "second", Delay: time.Hour},
+		)
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, mockedAPI.GetURL().String()+"/notifications", nil)
+		require.NoError(t, err)
+
+		// Act
+		response, err := mockedAPI.Client().Do(request)
+
+		// Assert
+		require.NoError(t, err)
+		line, err := bufio.NewReader(response.Body).ReadString('\n')
+		require.NoError(t, err)
+		assertions.Equal(t, "data: first\n", line)
+	})
+
+	t.Run("SSEDisconnect() should abort the stream", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/notifications").WithSSE(
+			SSEEvent{Data: "first"},
+			SSEDisconnect(10*time.Millisecond),
+			SSEEvent{Data: "never sent"},
+		)
+
+		// Act
+		response, err := http.Get(mockedAPI.GetURL().String() + "/notifications")
+
+		// Assert
+		require.NoError(t, err)
+		body, err := io.ReadAll(response.Body)
+		assert := assertions.New(t)
+		assert.Error(err)
+		assert.Equal("data: first\n\n", string(body))
+	})
+
+	t.Run("WithSSEStream() should push the events sent by the test", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		stream := mockedAPI.Stub(http.MethodGet, "/notifications").WithSSEStream()
+		stream.Send(SSEEvent{Data: "sent before connection"})
+		response, err := http.Get(mockedAPI.GetURL().String() + "/notifications")
+		require.NoError(t, err)
+		reader := bufio.NewReader(response.Body)
+
+		// Act
+		first := readSSEEvent(t, reader)
+		assertions.Eventually(t, func() bool { return stream.Connections() == 1 }, time.Second, time.Millisecond)
+		stream.Send(SSEEvent{Event: "created", Data: "pushed"})
+		second := readSSEEvent(t, reader)
+		stream.Close()
+		rest, err := io.ReadAll(reader)
+
+		// Assert
+		require.NoError(t, err)
+		assert := assertions.New(t)
+		assert.Equal("data: sent before connection\n", first)
+		assert.Equal("event: created\ndata: pushed\n", second)
+		assert.Empty(rest)
+		assert.Eventually(func() bool { return stream.Connections() == 0 }, time.Second, time.Millisecond)
+		mockedAPI.Verify(http.MethodGet, "/notifications").HasBeenCalledOnce()
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("WithSSEStream() should release the connection when the client disconnects", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		stream := mockedAPI.Stub(http.MethodGet, "/notifications").WithSSEStream()
+		response, err := http.Get(mockedAPI.GetURL().String() + "/notifications")
+		require.NoError(t, err)
+		assertions.Eventually(t, func() bool { return stream.Connections() == 1 }, time.Second, time.Millisecond)
+
+		// Act
+		_ = response.Body.Close()
+
+		// Assert
+		assertions.Eventually(t, func() bool { return stream.Connections() == 0 }, time.Second, time.Millisecond)
+	})
+}
+
+// readSSEEvent reads the lines of the next event, up to the blank line ending it.
+func readSSEEvent(t *testing.T, reader *bufio.Reader) string {
+	t.Helper()
+	event := strings.Builder{}
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if line == "\n" {
+			return event.String()
+		}
+		event.WriteString(line)
+	}
+}
@@ -0,0 +1,100 @@
+package mockhttp
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Chunk is a part of a streamed response body, see StubBuilder.WithStream.
+type Chunk struct {
+	Data []byte
+	// Delay is waited before sending the chunk.
+	Delay time.Duration
+}
+
+// NDJSONChunk returns the chunk of the JSON representation of the value as a newline delimited JSON line, sent once
+// the given delay has elapsed.
+func NDJSONChunk(value any, delay time.Duration) Chunk {
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return Chunk{Data: append(data, '\n'), Delay: delay}
+}
+
+// WithStream creates a new stub handler returning the specified status code then streaming the chunks, each one
+// being flushed once sent. The response has no Content-Length, HTTP/1.1 clients receiving it with the chunked
+// transfer encoding.
+func (stub *StubBuilder) WithStream(statusCode int, contentType string, chunks ...Chunk) *APIMock {
+	return stub.register(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", contentType)
+		writer.WriteHeader(statusCode)
+		_ = http.NewResponseController(writer).Flush()
+		for _, chunk := range chunks {
+			if !wait(request, chunk.Delay) || !writeChunk(writer, chunk.Data) {
+				return
+			}
+		}
+	}, nil)
+}
+
+// WithNDJSON creates a new stub handler returning the specified status code then streaming the values as newline
+// delimited JSON, one line per value. The response header "Content-Type" is set to "application/x-ndjson".
+func (stub *StubBuilder) WithNDJSON(statusCode int, values ...any) *APIMock {
+	chunks := make([]Chunk, 0, len(values))
+	for _, value := range values {
+		chunks = append(chunks, NDJSONChunk(value, 0))
+	}
+	return stub.WithStream(statusCode, "application/x-ndjson", chunks...)
+}
+
+// WithBodyReader creates a new stub handler returning the specified status code then streaming the body read from
+// the reader, each read being flushed once sent. As the reader can only be read once, only the first invocation gets
+// the body, the next ones getting an empty body.
+func (stub *StubBuilder) WithBodyReader(statusCode int, contentType string, reader io.Reader) *APIMock {
+	mu := sync.Mutex{}
+	return stub.register(func(writer http.ResponseWriter, request *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		writer.Header().Set("Content-Type", contentType)
+		writer.WriteHeader(statusCode)
+		_ = http.NewResponseController(writer).Flush()
+		buffer := make([]byte, 32*1024)
+		for request.Context().Err() == nil {
+			n, err := reader.Read(buffer)
+			if n > 0 && !writeChunk(writer, buffer[:n]) {
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}, nil)
+}
+
+// writeChunk sends then flushes the data, returning false when the client has disconnected.
+func writeChunk(writer http.ResponseWriter, data []byte) bool {
+	if _, err := writer.Write(data); err != nil {
+		return false
+	}
+	return http.NewResponseController(writer).Flush() == nil
+}
+
+// wait waits for the delay to elapse, returning false when the client has disconnected in the meantime.
+func wait(request *http.Request, delay time.Duration) bool {
+	if delay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-request.Context().Done():
+		return false
+	}
+}
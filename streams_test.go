@@ -0,0 +1,130 @@
+package mockhttp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Streams(t *testing.T) {
+	t.Parallel()
+
+	t.Run("WithStream() should stream the chunks", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/download").WithStream(http.StatusOK, "application/octet-stream",
+			Chunk{Data: []byte("first ")},
+			Chunk{Data: []byte("second"), Delay: 10 * time.Millisecond},
+		)
+
+		// Act
+		response, err := http.Get(mockedAPI.GetURL().String() + "/download")
+
+		// Assert
+		require.NoError(t, err)
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		assert := assertions.New(t)
+		assert.Equal(http.StatusOK, response.StatusCode)
+		assert.Equal("application/octet-stream", response.Header.Get("Content-Type"))
+		assert.Equal([]string{"chunked"}, response.TransferEncoding)
+		assert.Equal("first second", string(body))
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("WithStream() should flush each chunk", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/download").WithStream(http.StatusOK, "text/plain",
+			Chunk{Data: []byte("first\n")},
+			Chunk{Data: []byte("second\n"), Delay: time.Hour},
+		)
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, mockedAPI.GetURL().String()+"/download", nil)
+		require.NoError(t, err)
+
+		// Act
+		response, err := http.DefaultClient.Do(request)
+
+		// Assert
+		require.NoError(t, err)
+		line, err := bufio.NewReader(response.Body).ReadString('\n')
+		require.NoError(t, err)
+		assertions.Equal(t, "first\n", line)
+	})
+
+	t.Run("WithNDJSON() should stream a JSON line per value", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/events").WithNDJSON(http.StatusOK,
+			map[string]any{"id": 1},
+			map[string]any{"id": 2},
+		)
+
+		// Act
+		response, err := mockedAPI.Client().Get(mockedAPI.GetURL().String() + "/events")
+
+		// Assert
+		require.NoError(t, err)
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		assert := assertions.New(t)
+		assert.Equal("application/x-ndjson", response.Header.Get("Content-Type"))
+		assert.Equal("{\"id\":1}\n{\"id\":2}\n", string(body))
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("NDJSONChunk() should delay the JSON line", func(t *testing.T) {
+		t.Parallel()
+
+		chunk := NDJSONChunk(map[string]any{"progress": 50}, time.Second)
+
+		assert := assertions.New(t)
+		assert.Equal("{\"progress\":50}\n", string(chunk.Data))
+		assert.Equal(time.Second, chunk.Delay)
+	})
+
+	t.Run("WithBodyReader() should stream the body read", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/download").WithBodyReader(http.StatusOK, "text/plain", strings.NewReader("content"))
+
+		// Act
+		first, err := http.Get(mockedAPI.GetURL().String() + "/download")
+		require.NoError(t, err)
+		firstBody, err := io.ReadAll(first.Body)
+		require.NoError(t, err)
+		second, err := http.Get(mockedAPI.GetURL().String() + "/download")
+		require.NoError(t, err)
+		secondBody, err := io.ReadAll(second.Body)
+		require.NoError(t, err)
+
+		// Assert
+		assert := assertions.New(t)
+		assert.Equal("content", string(firstBody))
+		assert.Equal([]string{"chunked"}, first.TransferEncoding)
+		assert.Empty(secondBody)
+		testState.AssertDidNotFailed()
+	})
+}
@@ -0,0 +1,244 @@
+package mockhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadStubs registers the stubs declared in the given YAML or JSON stub file. When the path is a directory, every
+// .yaml, .yml and .json file it contains is loaded, in lexical order. See LoadStubsFS for the file format.
+func (mockedAPI *APIMock) LoadStubs(stubsPath string) *APIMock {
+	stubsPath = filepath.Clean(stubsPath)
+	return mockedAPI.LoadStubsFS(os.DirFS(filepath.Dir(stubsPath)), filepath.Base(stubsPath))
+}
+
+// LoadStubsFS registers the stubs declared in the YAML or JSON stub file of the given file system. When the path is
+// a directory, every .yaml, .yml and .json file it contains is loaded, in lexical order.
+//
+// A stub file lists the stubs under "stubs":
+//
+//	stubs:
+//	  - method: POST
+//	    path: /users/{id}/orders
+//	    host: api.example.com     # optional virtual host, see APIMock.Host
+//	    request:                  # optional matchers, all of them must match
+//	      query: {notify: "true"}
+//	      headers: {Authorization: Bearer token}
+//	      json: {item: book}      # or "body" for a raw body
+//	    scenario: {name: orders, requiredState: Started, newState: ordered}
+//	    delay: 100ms
+//	    response:
+//	      status: 201
+//	      headers: {Location: /orders/1}
+//	      json: {id: 1}           # or "body" for a raw body, or "bodyFile" for a file relative to the stub file
+//
+// As with stubs registered in code, the most recently loaded stub accepting a request handles it.
+func (mockedAPI *APIMock) LoadStubsFS(fsys fs.FS, stubsPath string) *APIMock {
+	files, err := stubFiles(fsys, stubsPath)
+	if err == nil {
+		for _, file := range files {
+			if err = loadStubFile(mockedAPI, fsys, file); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		mockedAPI.testState.Fatal(err)
+	}
+	return mockedAPI
+}
+
+type stubFile struct {
+	Stubs []*stubDefinition `yaml:"stubs"`
+}
+
+type stubDefinition struct {
+	Method   string                  `yaml:"method"`
+	Path     string                  `yaml:"path"`
+	Host     string                  `yaml:"host"`
+	Request  stubRequestDefinition   `yaml:"request"`
+	Scenario *stubScenarioDefinition `yaml:"scenario"`
+	Delay    string                  `yaml:"delay"`
+	Response stubResponseDefinition  `yaml:"response"`
+}
+
+type stubRequestDefinition struct {
+	Query   map[string]string `yaml:"query"`
+	Headers map[string]string `yaml:"headers"`
+	Body    *string           `yaml:"body"`
+	JSON    any               `yaml:"json"`
+}
+
+type stubScenarioDefinition struct {
+	Name          string `yaml:"name"`
+	RequiredState string `yaml:"requiredState"`
+	NewState      string `yaml:"newState"`
+}
+
+type stubResponseDefinition struct {
+	Status   int               `yaml:"status"`
+	Headers  map[string]string `yaml:"headers"`
+	Body     string            `yaml:"body"`
+	JSON     any               `yaml:"json"`
+	BodyFile string            `yaml:"bodyFile"`
+}
+
+// stubFiles returns the stub files found at the given path of the file system.
+func stubFiles(fsys fs.FS, stubsPath string) ([]string, error) {
+	info, err := fs.Stat(fsys, stubsPath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{stubsPath}, nil
+	}
+	entries, err := fs.ReadDir(fsys, stubsPath)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, entry := range entries {
+		switch path.Ext(entry.Name()) {
+		case ".yaml", ".yml", ".json":
+			if !entry.IsDir() {
+				files = append(files, path.Join(stubsPath, entry.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+func loadStubFile(mockedAPI *APIMock, fsys fs.FS, file string) error {
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return err
+	}
+	stubs := stubFile{}
+	if err := yaml.Unmarshal(data, &stubs); err != nil {
+		return fmt.Errorf("invalid stub file %s: %w", file, err)
+	}
+	for i, definition := range stubs.Stubs {
+		if _, err := definition.stub(mockedAPI, fsys, path.Dir(file)); err != nil {
+			return fmt.Errorf("invalid stub #%d of %s: %w", i+1, file, err)
+		}
+	}
+	return nil
+}
+
+// stub registers the stub described by the definition, its body files being relative to the given directory of the
+// file system. Body files are not supported when the file system is nil.
+func (definition *stubDefinition) stub(mockedAPI *APIMock, fsys fs.FS, dir string) (*registeredStub, error) {
+	if definition.Method == "" || definition.Path == "" {
+		return nil, errors.New("method and path are required")
+	}
+	builder := mockedAPI.Stub(definition.Method, definition.Path)
+	if definition.Host != "" {
+		builder = mockedAPI.Host(definition.Host).Stub(definition.Method, definition.Path)
+	}
+
+	for name, expected := range definition.Request.Query {
+		builder.Matching(func(request *http.Request, payload []byte) bool {
+			values, ok := request.URL.Query()[name]
+			return ok && slices.Contains(values, expected)
+		})
+	}
+	for name, expected := range definition.Request.Headers {
+		builder.Matching(func(request *http.Request, payload []byte) bool {
+			return slices.Contains(request.Header.Values(name), expected)
+		})
+	}
+	if definition.Request.Body != nil {
+		expected := *definition.Request.Body
+		builder.Matching(func(request *http.Request, payload []byte) bool {
+			return string(payload) == expected
+		})
+	}
+	if definition.Request.JSON != nil {
+		expected, err := normalizeJSON(definition.Request.JSON)
+		if err != nil {
+			return nil, err
+		}
+		builder.Matching(func(request *http.Request, payload []byte) bool {
+			var actual any
+			return json.Unmarshal(payload, &actual) == nil && reflect.DeepEqual(expected, actual)
+		})
+	}
+
+	if definition.Scenario != nil {
+		builder.InScenario(definition.Scenario.Name, definition.Scenario.RequiredState, definition.Scenario.NewState)
+	}
+	if definition.Delay != "" {
+		delay, err := time.ParseDuration(definition.Delay)
+		if err != nil {
+			return nil, err
+		}
+		builder.WithDelay(delay)
+	}
+
+	response, err := definition.Response.stubResponse(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	return builder.add(response.handler, response), nil
+}
+
+func (definition *stubResponseDefinition) stubResponse(fsys fs.FS, dir string) (*stubResponse, error) {
+	response := &stubResponse{
+		statusCode: definition.Status,
+		header:     http.Header{},
+		body:       []byte(definition.Body),
+	}
+	if response.statusCode == 0 {
+		response.statusCode = http.StatusOK
+	}
+	for name, value := range definition.Headers {
+		response.header.Set(name, value)
+	}
+
+	contentType := ""
+	switch {
+	case definition.JSON != nil:
+		body, err := json.Marshal(definition.JSON)
+		if err != nil {
+			return nil, err
+		}
+		response.body = body
+		contentType = "application/json"
+	case definition.BodyFile != "" && fsys == nil:
+		return nil, errors.New("bodyFile is not supported, use body or json")
+	case definition.BodyFile != "":
+		body, err := fs.ReadFile(fsys, path.Join(dir, definition.BodyFile))
+		if err != nil {
+			return nil, err
+		}
+		response.body = body
+		contentType = mime.TypeByExtension(path.Ext(definition.BodyFile))
+	}
+	if contentType != "" && response.header.Get("Content-Type") == "" {
+		response.header.Set("Content-Type", contentType)
+	}
+	return response, nil
+}
+
+// normalizeJSON converts a value decoded from YAML to the value decoded from its JSON representation.
+func normalizeJSON(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized any
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
@@ -0,0 +1,187 @@
+package mockhttp
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const usersStubFile = `
+stubs:
+  - method: GET
+    path: /users/{id}
+    response:
+      json: {id: 42, name: John}
+  - method: POST
+    path: /users
+    response:
+      status: 400
+  - method: POST
+    path: /users
+    request:
+      query: {notify: "true"}
+      headers: {Authorization: Bearer token}
+      json: {name: John}
+    delay: 10ms
+    response:
+      status: 201
+      headers: {Location: /users/42}
+      bodyFile: bodies/created.txt
+`
+
+func Test_LoadStubs(t *testing.T) {
+	t.Parallel()
+
+	send := func(t *testing.T, mockedAPI *APIMock, method string, path string, body string, header http.Header) (*http.Response, string) {
+		request, err := http.NewRequest(method, mockedAPI.GetURL().String()+path, strings.NewReader(body))
+		require.NoError(t, err)
+		for name, values := range header {
+			request.Header[name] = values
+		}
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		responseBody, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response, string(responseBody)
+	}
+
+	t.Run("LoadStubsFS() should stub the declared stubs", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		fsys := fstest.MapFS{
+			"stubs/users.yaml":         {Data: []byte(usersStubFile)},
+			"stubs/bodies/created.txt": {Data: []byte("created")},
+		}
+
+		// Act
+		mockedAPI.LoadStubsFS(fsys, "stubs/users.yaml")
+
+		// Assert
+		assert := assertions.New(t)
+		response, body := send(t, mockedAPI, http.MethodGet, "/users/42", "", nil)
+		assert.Equal(http.StatusOK, response.StatusCode)
+		assert.Equal("application/json", response.Header.Get("Content-Type"))
+		assert.JSONEq(`{"id": 42, "name": "John"}`, body)
+
+		start := time.Now()
+		response, body = send(t, mockedAPI, http.MethodPost, "/users?notify=true", `{"name": "John"}`, http.Header{"Authorization": {"Bearer token"}})
+		assert.Equal(http.StatusCreated, response.StatusCode)
+		assert.Equal("/users/42", response.Header.Get("Location"))
+		assert.Equal("text/plain; charset=utf-8", response.Header.Get("Content-Type"))
+		assert.Equal("created", body)
+		assert.GreaterOrEqual(time.Since(start), 10*time.Millisecond)
+
+		response, _ = send(t, mockedAPI, http.MethodPost, "/users?notify=true", `{"name": "Jane"}`, http.Header{"Authorization": {"Bearer token"}})
+		assert.Equal(http.StatusBadRequest, response.StatusCode)
+
+		mockedAPI.Verify(http.MethodPost, "/users").HasBeenCalled(2)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("LoadStubs() should load every stub file of a directory", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "health.json"),
+			[]byte(`{"stubs": [{"method": "GET", "path": "/health", "response": {"status": 204}}]}`), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "version.yml"),
+			[]byte("stubs:\n  - method: GET\n    path: /version\n    response: {body: v1}\n"), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a stub file"), 0o600))
+
+		// Act
+		mockedAPI.LoadStubs(dir)
+
+		// Assert
+		assert := assertions.New(t)
+		response, _ := send(t, mockedAPI, http.MethodGet, "/health", "", nil)
+		assert.Equal(http.StatusNoContent, response.StatusCode)
+		_, body := send(t, mockedAPI, http.MethodGet, "/version", "", nil)
+		assert.Equal("v1", body)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("LoadStubs() should load a directory given with a trailing slash", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "health.json"),
+			[]byte(`{"stubs": [{"method": "GET", "path": "/health", "response": {"status": 204}}]}`), 0o600))
+
+		// Act
+		mockedAPI.LoadStubs(dir + string(filepath.Separator))
+
+		// Assert
+		response, _ := send(t, mockedAPI, http.MethodGet, "/health", "", nil)
+		assertions.Equal(t, http.StatusNoContent, response.StatusCode)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("LoadStubsFS() should stub the virtual host of the stubs", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithoutListener())
+		t.Cleanup(mockedAPI.Close)
+		fsys := fstest.MapFS{
+			"stubs.yaml": {Data: []byte("stubs:\n  - method: GET\n    path: /health\n    host: api.example.com\n    response: {body: api}\n")},
+		}
+
+		// Act
+		mockedAPI.LoadStubsFS(fsys, "stubs.yaml")
+
+		// Assert
+		assertions.Equal(t, "api", getBody(t, mockedAPI.Client(), "https://api.example.com/health"))
+		mockedAPI.Host("api.example.com").Verify(http.MethodGet, "/health").HasBeenCalledOnce()
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("LoadStubs() should fail when the file does not exist", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		mockedAPI.LoadStubs(filepath.Join(t.TempDir(), "missing.yaml"))
+
+		// Assert
+		testState.AssertFailedWithFatal()
+	})
+
+	t.Run("LoadStubsFS() should fail on invalid stub", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		fsys := fstest.MapFS{
+			"stubs.yaml": {Data: []byte("stubs:\n  - method: GET\n    path: /users\n    delay: soon\n")},
+		}
+
+		// Act
+		mockedAPI.LoadStubsFS(fsys, "stubs.yaml")
+
+		// Assert
+		testState.AssertFailedWithFatal()
+	})
+}
@@ -4,27 +4,79 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"regexp"
 	"time"
 )
 
 // StubBuilder is a helper to build stubs for a specific HTTP call
 type StubBuilder struct {
-	api   *APIMock
-	call  *HTTPCall
-	delay time.Duration
+	api      *APIMock
+	call     *HTTPCall
+	pattern  *regexp.Regexp
+	delay    time.Duration
+	matchers []RequestMatcher
+	scenario *stubScenario
+	wireMock *wireMockRequest
 }
 
-// With creates a new stub for the HTTP call with the specified handler
+// RequestMatcher reports whether a stub handles the request, whose body is given as payload.
+type RequestMatcher func(request *http.Request, payload []byte) bool
+
+// registeredStub is a handler registered for an HTTP call.
+type registeredStub struct {
+	id       int
+	call     HTTPCall
+	handler  http.HandlerFunc
+	matchers []RequestMatcher
+	scenario *stubScenario
+	delay    time.Duration
+	// response is the definition of the stubbed response, nil when the stub uses a custom handler.
+	response *stubResponse
+	// wireMock is the request definition of a stub imported from a WireMock mapping.
+	wireMock *wireMockRequest
+}
+
+type stubResponse struct {
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
+// stubScenario restricts a stub to a state of a named scenario, and optionally makes the scenario transition to a
+// new state when the stub is called.
+type stubScenario struct {
+	name          string
+	requiredState string
+	newState      string
+}
+
+// ScenarioStartedState is the initial state of every scenario.
+const ScenarioStartedState = "Started"
+
+// With creates a new stub for the HTTP call with the specified handler.
+// The stubbed path may contain parameters (e.g. /pets/{id}) whose values are then available to the handler
+// through request.PathValue. Stubs registered for an exact path take precedence over templated ones.
 func (stub *StubBuilder) With(handler http.HandlerFunc) *APIMock {
-	if stub.delay > 0 {
-		stub.api.calls[*stub.call] = func(writer http.ResponseWriter, request *http.Request) {
-			time.Sleep(stub.delay)
-			handler(writer, request)
-		}
-	} else {
-		stub.api.calls[*stub.call] = handler
+	return stub.register(handler, nil)
+}
+
+// Matching restricts the stub to the requests accepted by the matcher. Several stubs can then be registered for the
+// same HTTP call, the most recently registered stub accepting the request handling it.
+func (stub *StubBuilder) Matching(matcher RequestMatcher) *StubBuilder {
+	stub.matchers = append(stub.matchers, matcher)
+	return stub
+}
+
+// InScenario restricts the stub to the given state of the named scenario, every scenario being initially in the
+// ScenarioStartedState state. An empty requiredState matches any state. When newState is not empty, the scenario
+// transitions to it once the stub is called.
+func (stub *StubBuilder) InScenario(name string, requiredState string, newState string) *StubBuilder {
+	stub.scenario = &stubScenario{
+		name:          name,
+		requiredState: requiredState,
+		newState:      newState,
 	}
-	return stub.api
+	return stub
 }
 
 // WithDelay adds a delay to the stub when called before it executes the corresponding handler
@@ -35,9 +87,9 @@ func (stub *StubBuilder) WithDelay(delay time.Duration) *StubBuilder {
 
 // WithStatusCode creates a new stub handler returning the specified status code
 func (stub *StubBuilder) WithStatusCode(statusCode int) *APIMock {
-	return stub.With(func(writer http.ResponseWriter, request *http.Request) {
+	return stub.register(func(writer http.ResponseWriter, request *http.Request) {
 		writer.WriteHeader(statusCode)
-	})
+	}, &stubResponse{statusCode: statusCode})
 }
 
 // WithJSON creates a new stub handler returning the specified status code and JSON content.
@@ -53,12 +105,98 @@ func (stub *StubBuilder) WithJSON(statusCode int, content any) *APIMock {
 
 // WithBody creates a new stub handler returning the specified status code and body content.
 func (stub *StubBuilder) WithBody(statusCode int, body []byte, contentType string) *APIMock {
-	return stub.With(func(writer http.ResponseWriter, request *http.Request) {
-		writer.Header().Add("Content-Type", contentType)
-		writer.WriteHeader(statusCode)
-		_, err := writer.Write(body)
-		if err != nil {
-			log.Fatal(err)
-		}
+	return stub.withResponse(&stubResponse{
+		statusCode: statusCode,
+		header:     http.Header{"Content-Type": []string{contentType}},
+		body:       body,
 	})
 }
+
+// withResponse creates a new stub handler returning the defined response.
+func (stub *StubBuilder) withResponse(response *stubResponse) *APIMock {
+	return stub.register(response.handler, response)
+}
+
+// handler writes the defined response.
+func (response *stubResponse) handler(writer http.ResponseWriter, request *http.Request) {
+	for name, values := range response.header {
+		for _, value := range values {
+			writer.Header().Add(name, value)
+		}
+	}
+	writer.WriteHeader(response.statusCode)
+	// as with net/http handlers, a failed write (e.g. the client has disconnected) is ignored
+	_, _ = writer.Write(response.body)
+}
+
+func (stub *StubBuilder) register(handler http.HandlerFunc, response *stubResponse) *APIMock {
+	stub.add(handler, response)
+	return stub.api
+}
+
+// add registers a new stub for the HTTP call and returns it.
+func (stub *StubBuilder) add(handler http.HandlerFunc, response *stubResponse) *registeredStub {
+	if stub.delay > 0 {
+		delayedHandler := handler
+		handler = func(writer http.ResponseWriter, request *http.Request) {
+			time.Sleep(stub.delay)
+			delayedHandler(writer, request)
+		}
+	}
+	registered := &registeredStub{
+		call:     *stub.call,
+		handler:  handler,
+		matchers: stub.matchers,
+		scenario: stub.scenario,
+		delay:    stub.delay,
+		response: response,
+		wireMock: stub.wireMock,
+	}
+
+	mockedAPI := stub.api
+	mockedAPI.mu.Lock()
+	defer mockedAPI.mu.Unlock()
+
+	stubs, ok := mockedAPI.calls[*stub.call]
+	if !ok {
+		switch {
+		case stub.pattern != nil:
+			mockedAPI.templates = append(mockedAPI.templates, &pathTemplate{call: *stub.call, pattern: stub.pattern, regexp: true})
+		case isPathTemplate(stub.call.Path):
+			mockedAPI.templates = append(mockedAPI.templates, newPathTemplate(*stub.call))
+		}
+	}
+	if registered.isUnconditional() {
+		// an unconditional stub replaces the previous unconditional one
+		conditionalStubs := make([]*registeredStub, 0, len(stubs))
+		for _, existing := range stubs {
+			if !existing.isUnconditional() {
+				conditionalStubs = append(conditionalStubs, existing)
+			}
+		}
+		stubs = conditionalStubs
+	}
+	activeCoverage.Load().registerStub(mockedAPI.name, registered.call)
+	mockedAPI.stubSequence++
+	registered.id = mockedAPI.stubSequence
+	mockedAPI.calls[*stub.call] = append(stubs, registered)
+	return registered
+}
+
+func (registered *registeredStub) isUnconditional() bool {
+	return len(registered.matchers) == 0 && registered.scenario == nil
+}
+
+// accepts reports whether the stub handles the request, the scenarios being in the given states.
+func (registered *registeredStub) accepts(scenarios map[string]string, request *http.Request, payload []byte) bool {
+	if registered.scenario != nil && registered.scenario.requiredState != "" &&
+		scenarioState(scenarios, registered.scenario.name) != registered.scenario.requiredState {
+		return false
+	}
+	for _, matcher := range registered.matchers {
+		if !matcher(request, payload) {
+			return false
+		}
+	}
+	return true
+}
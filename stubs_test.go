@@ -98,6 +98,94 @@ func Test_StubbedEndpoint(t *testing.T) {
 		testState.AssertDidNotFailed()
 	})
 
+	t.Run("can stub templated paths", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+
+		mockedAPI.
+			Stub(http.MethodGet, "/users/{id}/orders/{orderId}").
+			With(func(writer http.ResponseWriter, request *http.Request) {
+				writer.WriteHeader(http.StatusOK)
+				_, err := writer.Write([]byte(request.PathValue("id") + "-" + request.PathValue("orderId")))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}).
+			Stub(http.MethodGet, "/users/me/orders/last").
+			WithStatusCode(http.StatusNoContent)
+
+		// Act
+		templatedCall := mockedAPI.testCall(http.MethodGet, "/users/42/orders/7", t)
+		exactCall := mockedAPI.testCall(http.MethodGet, "/users/me/orders/last", t)
+
+		// Assert
+		testState.AssertDidNotFailed()
+		templatedCall.
+			Status(http.StatusOK).
+			Body().IsEqual("42-7")
+		exactCall.Status(http.StatusNoContent)
+	})
+
+	t.Run("sets the path values of the selected template only", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+
+		mockedAPI.
+			Stub(http.MethodGet, "/{y}/b").
+			With(func(writer http.ResponseWriter, request *http.Request) {
+				writer.WriteHeader(http.StatusOK)
+				_, err := writer.Write([]byte("x=" + request.PathValue("x") + " y=" + request.PathValue("y")))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}).
+			Stub(http.MethodGet, "/a/{x}").
+			Matching(func(request *http.Request, _ []byte) bool { return request.PathValue("x") == "c" }).
+			WithStatusCode(http.StatusNoContent)
+
+		// Act
+		call := mockedAPI.testCall(http.MethodGet, "/a/b", t)
+
+		// Assert
+		testState.AssertDidNotFailed()
+		call.
+			Status(http.StatusOK).
+			Body().IsEqual("x= y=a")
+	})
+
+	t.Run("can read the scenario state from a matcher", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+
+		mockedAPI.
+			Stub(http.MethodGet, "/endpoint").
+			WithStatusCode(http.StatusNotFound).
+			Stub(http.MethodGet, "/endpoint").
+			Matching(func(*http.Request, []byte) bool {
+				return mockedAPI.GetScenarioState("login") == ScenarioStartedState
+			}).
+			InScenario("login", "", "logged").
+			WithStatusCode(http.StatusOK)
+
+		// Act
+		firstCall := mockedAPI.testCall(http.MethodGet, "/endpoint", t)
+		secondCall := mockedAPI.testCall(http.MethodGet, "/endpoint", t)
+
+		// Assert
+		testState.AssertDidNotFailed()
+		firstCall.Status(http.StatusOK)
+		secondCall.Status(http.StatusNotFound)
+	})
+
 	t.Run("can return json response", func(t *testing.T) {
 		t.Parallel()
 		// Arrange
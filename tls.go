@@ -0,0 +1,100 @@
+package mockhttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+)
+
+// APIWithTLS creates a new APIMock instance and starts a TLS server exposing it, GetURL then returning an https URL.
+// By default the server uses a self-signed certificate valid for 127.0.0.1 and example.com, trusted by the clients
+// returned by TLSClient or configured with CertPool. The server is automatically stopped during test cleanup.
+func APIWithTLS(testState T, options ...Option) *APIMock {
+	return API(testState, append([]Option{withTLS()}, options...)...)
+}
+
+func withTLS() Option {
+	return func(mockedAPI *APIMock) {
+		mockedAPI.getTLSConfig()
+	}
+}
+
+// WithTLSCertificate makes the TLS server use the given certificate, whose chain must be valid for the host of
+// GetURL (127.0.0.1 unless WithAddress is used). It implies a TLS server, see APIWithTLS.
+func WithTLSCertificate(certificate tls.Certificate) Option {
+	return func(mockedAPI *APIMock) {
+		mockedAPI.getTLSConfig().Certificates = []tls.Certificate{certificate}
+	}
+}
+
+// WithTLSVersions restricts the TLS versions accepted by the server (e.g. tls.VersionTLS12), a zero value keeping
+// the default minimum or maximum. It implies a TLS server, see APIWithTLS.
+func WithTLSVersions(minVersion uint16, maxVersion uint16) Option {
+	return func(mockedAPI *APIMock) {
+		mockedAPI.getTLSConfig().MinVersion = minVersion
+		mockedAPI.getTLSConfig().MaxVersion = maxVersion
+	}
+}
+
+// WithTLSCipherSuites restricts the cipher suites accepted by the server (e.g. tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256).
+// As with crypto/tls, TLS 1.3 cipher suites are not configurable. It implies a TLS server, see APIWithTLS.
+func WithTLSCipherSuites(cipherSuites ...uint16) Option {
+	return func(mockedAPI *APIMock) {
+		mockedAPI.getTLSConfig().CipherSuites = cipherSuites
+	}
+}
+
+// WithClientCertificates makes the TLS server require a client certificate signed by one of the given certificate
+// authorities, or any client certificate when clientCAs is nil. The certificates presented by the clients can then be
+// asserted on the invocations, see Invocation.WithClientCertificateSubject. It implies a TLS server, see APIWithTLS.
+func WithClientCertificates(clientCAs *x509.CertPool) Option {
+	return func(mockedAPI *APIMock) {
+		config := mockedAPI.getTLSConfig()
+		config.ClientCAs = clientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		if clientCAs == nil {
+			config.ClientAuth = tls.RequireAnyClientCert
+		}
+	}
+}
+
+// getTLSConfig returns the TLS configuration of the server, creating it when the server does not use TLS yet.
+func (mockedAPI *APIMock) getTLSConfig() *tls.Config {
+	if mockedAPI.tlsConfig == nil {
+		mockedAPI.tlsConfig = &tls.Config{}
+	}
+	return mockedAPI.tlsConfig
+}
+
+// CertPool returns the certificate pool trusting the certificate of the TLS server, nil when the server does not use
+// TLS. It is meant to be set as the RootCAs of the TLS configuration of the clients.
+func (mockedAPI *APIMock) CertPool() *x509.CertPool {
+	if mockedAPI.testServer == nil || mockedAPI.testServer.TLS == nil {
+		return nil
+	}
+	pool := x509.NewCertPool()
+	for _, certificate := range mockedAPI.testServer.TLS.Certificates[0].Certificate {
+		if parsed, err := x509.ParseCertificate(certificate); err == nil {
+			pool.AddCert(parsed)
+		}
+	}
+	return pool
+}
+
+// TLSClient returns an http.Client trusting the certificate of the TLS server. When the server does not use TLS, the
+// client is a plain HTTP client.
+func (mockedAPI *APIMock) TLSClient() *http.Client {
+	if mockedAPI.testServer == nil {
+		return &http.Client{}
+	}
+	return mockedAPI.testServer.Client()
+}
+
+// TLSClientWithCertificate returns an http.Client trusting the certificate of the TLS server and presenting the given
+// client certificate, see WithClientCertificates.
+func (mockedAPI *APIMock) TLSClientWithCertificate(certificate tls.Certificate) *http.Client {
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      mockedAPI.CertPool(),
+		Certificates: []tls.Certificate{certificate},
+	}}}
+}
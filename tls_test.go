@@ -0,0 +1,229 @@
+package mockhttp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_APIWithTLS(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should serve the stubs over TLS", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := APIWithTLS(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/health").WithStatusCode(http.StatusNoContent)
+
+		// Act
+		response, err := mockedAPI.TLSClient().Get(mockedAPI.GetURL().String() + "/health")
+
+		// Assert
+		require.NoError(t, err)
+		assert := assertions.New(t)
+		assert.Equal("https", mockedAPI.GetURL().Scheme)
+		assert.Equal(http.StatusNoContent, response.StatusCode)
+		assert.NotNil(response.TLS)
+		mockedAPI.Verify(http.MethodGet, "/health").HasBeenCalledOnce()
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("CertPool() should be trusted by clients", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := APIWithTLS(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/health").WithStatusCode(http.StatusNoContent)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: mockedAPI.CertPool()}}}
+
+		// Act
+		response, err := client.Get(mockedAPI.GetURL().String() + "/health")
+
+		// Assert
+		require.NoError(t, err)
+		assertions.Equal(t, http.StatusNoContent, response.StatusCode)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should reject clients not trusting the certificate", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := APIWithTLS(testState)
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		_, err := http.Get(mockedAPI.GetURL().String() + "/health")
+
+		// Assert
+		assertions.Error(t, err)
+	})
+
+	t.Run("WithTLSCertificate() should use the certificate", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		certificate := newTestCertificate(t, "mock server")
+		mockedAPI := APIWithTLS(testState, WithTLSCertificate(certificate))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/health").WithStatusCode(http.StatusNoContent)
+
+		// Act
+		response, err := mockedAPI.TLSClient().Get(mockedAPI.GetURL().String() + "/health")
+
+		// Assert
+		require.NoError(t, err)
+		assert := assertions.New(t)
+		assert.Equal(http.StatusNoContent, response.StatusCode)
+		assert.Equal("mock server", response.TLS.PeerCertificates[0].Subject.CommonName)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("WithTLSVersions() should reject other versions", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := APIWithTLS(testState, WithTLSVersions(tls.VersionTLS13, 0))
+		t.Cleanup(mockedAPI.Close)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:    mockedAPI.CertPool(),
+			MaxVersion: tls.VersionTLS12,
+		}}}
+
+		// Act
+		_, err := client.Get(mockedAPI.GetURL().String() + "/health")
+
+		// Assert
+		assertions.Error(t, err)
+	})
+
+	t.Run("WithTLSCipherSuites() should negotiate the cipher suites", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := APIWithTLS(testState,
+			WithTLSVersions(0, tls.VersionTLS12),
+			WithTLSCipherSuites(tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384),
+			WithTLSCertificate(newTestCertificate(t, "mock server")))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/health").WithStatusCode(http.StatusNoContent)
+
+		// Act
+		response, err := mockedAPI.TLSClient().Get(mockedAPI.GetURL().String() + "/health")
+
+		// Assert
+		require.NoError(t, err)
+		assert := assertions.New(t)
+		assert.Equal(uint16(tls.VersionTLS12), response.TLS.Version)
+		assert.Equal(tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, response.TLS.CipherSuite)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("WithClientCertificates() should accept trusted client certificates", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		clientCertificate := newTestCertificate(t, "payments", "payments.example.com")
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(clientCertificate.Leaf)
+		mockedAPI := APIWithTLS(testState, WithClientCertificates(clientCAs))
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodPost, "/payments").WithStatusCode(http.StatusCreated)
+
+		// Act
+		response, err := mockedAPI.TLSClientWithCertificate(clientCertificate).Post(mockedAPI.GetURL().String()+"/payments", "", nil)
+
+		// Assert
+		require.NoError(t, err)
+		assertions.Equal(t, http.StatusCreated, response.StatusCode)
+		mockedAPI.Verify(http.MethodPost, "/payments").HasBeenCalledOnce().
+			WithClientCertificateSubject("CN=payments").
+			WithClientCertificateSubject("payments").
+			WithClientCertificateSAN("payments.example.com").
+			WithClientCertificateSAN("127.0.0.1").
+			WithTLSVersion(tls.VersionTLS13)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("WithClientCertificates() should reject untrusted client certificates", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(newTestCertificate(t, "payments").Leaf)
+		mockedAPI := APIWithTLS(testState, WithClientCertificates(clientCAs))
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		_, err := mockedAPI.TLSClientWithCertificate(newTestCertificate(t, "intruder")).Get(mockedAPI.GetURL().String() + "/payments")
+
+		// Assert
+		assertions.Error(t, err)
+	})
+
+	t.Run("WithClientCertificates() should reject clients without certificate", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := APIWithTLS(testState, WithClientCertificates(nil))
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		_, err := mockedAPI.TLSClient().Get(mockedAPI.GetURL().String() + "/payments")
+
+		// Assert
+		assertions.Error(t, err)
+	})
+
+	t.Run("should fail without listener", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+
+		// Act
+		mockedAPI := APIWithTLS(testState, WithoutListener())
+		t.Cleanup(mockedAPI.Close)
+
+		// Assert
+		testState.AssertFailedWithFatal()
+	})
+}
+
+// newTestCertificate returns a self-signed certificate valid for 127.0.0.1 and the given DNS names.
+func newTestCertificate(t *testing.T, commonName string, dnsNames ...string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:              dnsNames,
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(certificate)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{certificate}, PrivateKey: key, Leaf: leaf}
+}
@@ -0,0 +1,235 @@
+package mockhttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// inMemoryURL is the URL of an API mock created with the WithoutListener option.
+const inMemoryURL = "http://mockhttp.invalid"
+
+// WithoutListener creates the API mock without starting its server, the mocked API then only being reachable through
+// its in-memory transport (see APIMock.Transport and APIMock.Client). GetURL returns http://mockhttp.invalid.
+// It avoids opening a port per API mock in large test suites.
+func WithoutListener() Option {
+	return func(mockedAPI *APIMock) {
+		mockedAPI.withoutListener = true
+	}
+}
+
+// Transport returns an http.RoundTripper dispatching the requests directly to the mocked API, without any network
+// connection, whatever the host of their URL. Responses are streamed, the handlers flushing their writes as they go.
+func (mockedAPI *APIMock) Transport() http.RoundTripper {
+	return &inMemoryTransport{handler: http.HandlerFunc(mockedAPI.serveHTTP)}
+}
+
+// Client returns an http.Client using the in-memory transport of the mocked API, see Transport.
+func (mockedAPI *APIMock) Client() *http.Client {
+	return &http.Client{Transport: mockedAPI.Transport()}
+}
+
+// inMemoryTransport serves the requests with a handler running in the same process.
+type inMemoryTransport struct {
+	handler http.Handler
+}
+
+func (transport *inMemoryTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx := request.Context()
+	// as with a network connection, the handler request context is canceled once the client closes the body
+	serverCtx, cancel := context.WithCancel(ctx)
+	serverRequest := request.Clone(serverCtx)
+	serverRequest.URL = &url.URL{Path: request.URL.Path, RawPath: request.URL.RawPath, RawQuery: request.URL.RawQuery}
+	serverRequest.RequestURI = request.URL.RequestURI()
+	serverRequest.Proto, serverRequest.ProtoMajor, serverRequest.ProtoMinor = "HTTP/1.1", 1, 1
+	serverRequest.RemoteAddr = "in-memory"
+	if serverRequest.Host == "" {
+		serverRequest.Host = request.URL.Host
+	}
+	if serverRequest.Body == nil {
+		serverRequest.Body = http.NoBody
+	}
+
+	writer := newInMemoryResponseWriter()
+	writer.body.onClose = cancel
+	stop := context.AfterFunc(ctx, func() {
+		writer.body.closeWithError(ctx.Err())
+	})
+	go func() {
+		defer cancel()
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			stop()
+			recovered := recover()
+			switch {
+			case recovered == http.ErrAbortHandler:
+				writer.fail(errors.New("mockhttp: handler aborted the response"))
+			case recovered != nil:
+				writer.fail(fmt.Errorf("mockhttp: handler panicked: %v", recovered))
+			default:
+				// the handler goroutine has exited, e.g. with runtime.Goexit when the test state fails with Fatal
+				writer.fail(errors.New("mockhttp: handler exited before completing the response"))
+			}
+		}()
+		transport.handler.ServeHTTP(writer, serverRequest)
+		completed = true
+		stop()
+		writer.WriteHeader(http.StatusOK)
+		writer.body.closeWithError(io.EOF)
+	}()
+
+	select {
+	case <-writer.headerWritten:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if writer.err != nil {
+		return nil, writer.err
+	}
+
+	response := &http.Response{
+		Status:        fmt.Sprintf("%d %s", writer.statusCode, http.StatusText(writer.statusCode)),
+		StatusCode:    writer.statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        writer.sentHeader,
+		Body:          writer.body,
+		ContentLength: -1,
+		Request:       request,
+	}
+	if contentLength, err := strconv.ParseInt(writer.sentHeader.Get("Content-Length"), 10, 64); err == nil {
+		response.ContentLength = contentLength
+	}
+	if request.Method == http.MethodHead {
+		response.Body = http.NoBody
+	}
+	return response, nil
+}
+
+// inMemoryResponseWriter is the response writer of the in-memory transport, the response being returned to the
+// client as soon as its header is written.
+type inMemoryResponseWriter struct {
+	header        http.Header
+	sentHeader    http.Header
+	statusCode    int
+	body          *inMemoryBody
+	headerWritten chan struct{}
+	once          sync.Once
+	err           error
+}
+
+func newInMemoryResponseWriter() *inMemoryResponseWriter {
+	writer := &inMemoryResponseWriter{
+		header:        http.Header{},
+		body:          &inMemoryBody{},
+		headerWritten: make(chan struct{}),
+	}
+	writer.body.cond = sync.NewCond(&writer.body.mu)
+	return writer
+}
+
+func (writer *inMemoryResponseWriter) Header() http.Header {
+	return writer.header
+}
+
+func (writer *inMemoryResponseWriter) WriteHeader(statusCode int) {
+	if statusCode >= 100 && statusCode < 200 {
+		return
+	}
+	writer.once.Do(func() {
+		writer.statusCode = statusCode
+		writer.sentHeader = writer.header.Clone()
+		close(writer.headerWritten)
+	})
+}
+
+func (writer *inMemoryResponseWriter) Write(data []byte) (int, error) {
+	writer.WriteHeader(http.StatusOK)
+	return writer.body.write(data)
+}
+
+// Flush sends the response header if not sent yet, the written body being always readable by the client.
+func (writer *inMemoryResponseWriter) Flush() {
+	writer.WriteHeader(http.StatusOK)
+}
+
+// fail aborts the response, the client getting the error instead of the response when its header is not sent yet.
+func (writer *inMemoryResponseWriter) fail(err error) {
+	writer.once.Do(func() {
+		writer.err = err
+		close(writer.headerWritten)
+	})
+	writer.body.closeWithError(err)
+}
+
+// inMemoryBody is a response body written by the handler while being read by the client. Writes never block, the
+// body being buffered until read.
+type inMemoryBody struct {
+	buffer bytes.Buffer
+	closed bool
+	err    error
+	// onClose is called once the client closes the body.
+	onClose func()
+	mu      sync.Mutex
+	cond    *sync.Cond
+}
+
+// write buffers the data, which is discarded once the body is closed, as with a server whose client has gone away.
+func (body *inMemoryBody) write(data []byte) (int, error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+	if body.closed {
+		return len(data), nil
+	}
+	defer body.cond.Broadcast()
+	return body.buffer.Write(data)
+}
+
+func (body *inMemoryBody) Read(data []byte) (int, error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+	for body.buffer.Len() == 0 && !body.closed {
+		body.cond.Wait()
+	}
+	if body.buffer.Len() > 0 {
+		return body.buffer.Read(data)
+	}
+	return 0, body.err
+}
+
+// Close discards the unread body, the handler writes being discarded and its request context canceled from now on.
+func (body *inMemoryBody) Close() error {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+	body.buffer.Reset()
+	if !body.closed {
+		body.closed = true
+		body.err = errors.New("mockhttp: read on closed response body")
+		body.cond.Broadcast()
+	}
+	if body.onClose != nil {
+		body.onClose()
+	}
+	return nil
+}
+
+// closeWithError ends the body, the reads returning the error once the buffered data has been read.
+func (body *inMemoryBody) closeWithError(err error) {
+	body.mu.Lock()
+	defer body.mu.Unlock()
+	if !body.closed {
+		body.closed = true
+		body.err = err
+		body.cond.Broadcast()
+	}
+}
@@ -0,0 +1,216 @@
+package mockhttp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Transport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Client() should dispatch the requests to the stubs", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodPost, "/users/{id}").WithJSON(http.StatusCreated, map[string]any{"id": 42})
+
+		// Act
+		response, err := mockedAPI.Client().Post("http://users.example.com/users/42?notify=true", "application/json", strings.NewReader(`{"name": "John"}`))
+
+		// Assert
+		require.NoError(t, err)
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		assert := assertions.New(t)
+		assert.Equal(http.StatusCreated, response.StatusCode)
+		assert.Equal("application/json", response.Header.Get("Content-Type"))
+		assert.JSONEq(`{"id": 42}`, string(body))
+		mockedAPI.Verify(http.MethodPost, "/users/42").HasBeenCalledOnce().
+			WithQueryValue("notify", "true").
+			WithJSONPayload(map[string]any{"name": "John"})
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("WithoutListener() should not start the server", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+
+		// Act
+		mockedAPI := API(testState, WithoutListener())
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/health").WithStatusCode(http.StatusNoContent)
+
+		// Assert
+		assert := assertions.New(t)
+		assert.Nil(mockedAPI.testServer)
+		assert.Equal("http://mockhttp.invalid", mockedAPI.GetURL().String())
+		response, err := mockedAPI.Client().Get(mockedAPI.GetURL().String() + "/health")
+		require.NoError(t, err)
+		assert.Equal(http.StatusNoContent, response.StatusCode)
+		mockedAPI.Verify(http.MethodGet, "/health").HasBeenCalledOnce()
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("Transport() should stream the responses", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithoutListener())
+		t.Cleanup(mockedAPI.Close)
+		release := make(chan struct{})
+		mockedAPI.Stub(http.MethodGet, "/events").With(func(writer http.ResponseWriter, request *http.Request) {
+			_, _ = io.WriteString(writer, "first\n")
+			writer.(http.Flusher).Flush()
+			<-release
+			_, _ = io.WriteString(writer, "second\n")
+		})
+
+		// Act
+		response, err := mockedAPI.Client().Get("http://mockhttp.invalid/events")
+
+		// Assert
+		require.NoError(t, err)
+		reader := bufio.NewReader(response.Body)
+		first, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		close(release)
+		second, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		_, err = reader.ReadString('\n')
+		assert := assertions.New(t)
+		assert.Equal("first\n", first)
+		assert.Equal("second\n", second)
+		assert.ErrorIs(err, io.EOF)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("Transport() should fail when the handler aborts", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithoutListener())
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/abort").With(func(writer http.ResponseWriter, request *http.Request) {
+			panic(http.ErrAbortHandler)
+		})
+
+		// Act
+		_, err := mockedAPI.Client().Get("http://mockhttp.invalid/abort")
+
+		// Assert
+		assertions.New(t).ErrorContains(err, "handler aborted the response")
+	})
+
+	t.Run("Transport() should cancel the request with its context", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithoutListener())
+		t.Cleanup(mockedAPI.Close)
+		canceled := make(chan struct{})
+		mockedAPI.Stub(http.MethodGet, "/slow").With(func(writer http.ResponseWriter, request *http.Request) {
+			<-request.Context().Done()
+			close(canceled)
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		t.Cleanup(cancel)
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://mockhttp.invalid/slow", nil)
+		require.NoError(t, err)
+
+		// Act
+		_, err = mockedAPI.Client().Do(request)
+
+		// Assert
+		assertions.New(t).ErrorIs(err, context.DeadlineExceeded)
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Fatal("the handler request context has not been canceled")
+		}
+	})
+
+	t.Run("Transport() should discard the writes once the client has closed the body", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithoutListener())
+		t.Cleanup(mockedAPI.Close)
+		closed := make(chan struct{})
+		type writeResult struct {
+			n   int
+			err error
+			ctx error
+		}
+		written := make(chan writeResult, 1)
+		mockedAPI.Stub(http.MethodGet, "/download").With(func(writer http.ResponseWriter, request *http.Request) {
+			writer.WriteHeader(http.StatusOK)
+			<-closed
+			n, err := writer.Write([]byte("late"))
+			written <- writeResult{n: n, err: err, ctx: request.Context().Err()}
+		})
+		response, err := mockedAPI.Client().Get("http://mockhttp.invalid/download")
+		require.NoError(t, err)
+
+		// Act
+		require.NoError(t, response.Body.Close())
+		close(closed)
+
+		// Assert
+		result := <-written
+		assert := assertions.New(t)
+		assert.Equal(4, result.n)
+		assert.NoError(result.err)
+		assert.ErrorIs(result.ctx, context.Canceled)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("Transport() should end the body when the handler goroutine exits", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := &goexitT{MockedT: testingmock.New(t)}
+		mockedAPI := API(testState, WithoutListener())
+		t.Cleanup(mockedAPI.Close)
+		read := make(chan error, 1)
+
+		// Act
+		response, err := mockedAPI.Client().Get("http://mockhttp.invalid/unmocked")
+		require.NoError(t, err)
+		go func() {
+			_, err := io.ReadAll(response.Body)
+			read <- err
+		}()
+
+		// Assert
+		assertions.Equal(t, http.StatusNotFound, response.StatusCode)
+		select {
+		case err := <-read:
+			assertions.ErrorContains(t, err, "handler exited before completing the response")
+		case <-time.After(time.Second):
+			t.Fatal("the response body has not been ended")
+		}
+		testState.AssertFailedWithFatal()
+	})
+}
+
+// goexitT is a mocked test state exiting the calling goroutine on fatal failures, as testing.T does.
+type goexitT struct {
+	*testingmock.MockedT
+}
+
+func (testState *goexitT) Fatalf(format string, args ...any) {
+	testState.MockedT.Fatalf(format, args...)
+	runtime.Goexit()
+}
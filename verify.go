@@ -9,11 +9,20 @@ type CallVerifier struct {
 // HasBeenCalled asserts that the HTTP call has been made the expected number of times.
 // It returns all invocations of the call.
 func (verifier *CallVerifier) HasBeenCalled(expectedCallsCount int) []*Invocation {
-	invocations := verifier.api.invocations[*verifier.call]
+	call := HTTPCall{Method: verifier.call.Method, Path: verifier.call.Path}
+	verifier.api.mu.Lock()
+	invocations := append([]*Invocation{}, verifier.api.invocations[call]...)
+	verifier.api.mu.Unlock()
+	if verifier.call.Host != "" {
+		invocations = filterVirtualHost(invocations, verifier.call.Host)
+	}
 	actualCallsCount := len(invocations)
 	if actualCallsCount != expectedCallsCount {
 		verifier.api.testState.Fatalf("got %d http calls but was expecting %d\n", actualCallsCount, expectedCallsCount)
 	}
+	for _, invocation := range invocations {
+		invocation.markVerified()
+	}
 	return invocations
 }
 
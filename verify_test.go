@@ -38,6 +38,30 @@ func Test_Verify_Endpoint(t *testing.T) {
 			testState.AssertDidNotFailed()
 		})
 
+		t.Run("can run while the invocations are reset", func(t *testing.T) {
+			t.Parallel()
+			// Arrange
+			testState := testingmock.New(t)
+			mockedAPI := API(testState)
+			t.Cleanup(mockedAPI.Close)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for range 100 {
+					mockedAPI.ResetInvocations()
+				}
+			}()
+
+			// Act
+			for range 100 {
+				mockedAPI.Verify(http.MethodGet, "/endpoint").HasNotBeenCalled()
+			}
+			<-done
+
+			// Assert
+			testState.AssertDidNotFailed()
+		})
+
 		t.Run("fails when the endpoint was not called the expected number of times", func(t *testing.T) {
 			t.Parallel()
 			// Arrange
@@ -100,6 +124,29 @@ func Test_Verify_Endpoint(t *testing.T) {
 		})
 	})
 
+	t.Run("records the served responses", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+
+		mockedAPI.
+			Stub(http.MethodGet, "/endpoint").
+			WithBody(http.StatusAccepted, []byte("Hello"), "text/plain")
+		_, _ = http.Get(mockedAPI.GetURL().String() + "/endpoint")
+
+		// Act
+		call := mockedAPI.Verify(http.MethodGet, "/endpoint").HasBeenCalledOnce()
+
+		// Assert
+		testState.AssertDidNotFailed()
+		assert := assertions.New(t)
+		assert.Equal(http.StatusAccepted, call.GetResponse().StatusCode)
+		assert.Equal("text/plain", call.GetResponse().Header.Get("Content-Type"))
+		assert.Equal([]byte("Hello"), call.GetResponsePayload())
+	})
+
 	t.Run("HasBeenCalledOnce()", func(t *testing.T) {
 		t.Parallel()
 
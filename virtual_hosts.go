@@ -0,0 +1,72 @@
+package mockhttp
+
+import (
+	"net"
+	"strings"
+)
+
+// VirtualHost is a host served by a mocked API, whose stubs and verifications only apply to the requests sent to it,
+// requests being dispatched on their Host header.
+type VirtualHost struct {
+	api  *APIMock
+	host string
+}
+
+// Host returns the virtual host of the given name, so that a single mocked API can serve several hosts:
+//
+//	mockedAPI.Host("auth.example.com").Stub(http.MethodPost, "/token").WithJSON(http.StatusOK, token)
+//	mockedAPI.Host("api.example.com").Stub(http.MethodGet, "/users").WithJSON(http.StatusOK, users)
+//
+// When the host includes no port, it matches the requests sent to any port. When it includes the default port (80 or
+// 443), it also matches the requests whose Host header omits it. Stubs registered for the virtual host of
+// a request take precedence over the ones registered with APIMock.Stub, which apply to any host.
+// It is typically combined with APIMock.InterceptHosts, which keeps the original Host header of the requests.
+func (mockedAPI *APIMock) Host(host string) *VirtualHost {
+	return &VirtualHost{
+		api:  mockedAPI,
+		host: strings.ToLower(host),
+	}
+}
+
+// Stub creates a new StubBuilder instance for the given method and path of the virtual host.
+func (virtualHost *VirtualHost) Stub(method string, path string) *StubBuilder {
+	stub := virtualHost.api.Stub(method, path)
+	stub.call.Host = virtualHost.host
+	return stub
+}
+
+// Verify creates a new CallVerifier instance for the given method and path of the virtual host, only the invocations
+// sent to the virtual host being verified.
+func (virtualHost *VirtualHost) Verify(method string, path string) *CallVerifier {
+	verifier := virtualHost.api.Verify(method, path)
+	verifier.call.Host = virtualHost.host
+	return verifier
+}
+
+// virtualHostCandidates returns the virtual hosts a request sent to the given host may match, by order of precedence:
+// the host itself, its name without the port, then any host. As clients omit the default port from the Host header,
+// a host without port also matches the virtual hosts with the HTTP or HTTPS default port.
+func virtualHostCandidates(requestHost string) []string {
+	requestHost = strings.ToLower(requestHost)
+	candidates := []string{requestHost}
+	if hostname, _, err := net.SplitHostPort(requestHost); err == nil {
+		candidates = append(candidates, hostname)
+	} else {
+		hostname := strings.Trim(requestHost, "[]")
+		candidates = append(candidates, net.JoinHostPort(hostname, "443"), net.JoinHostPort(hostname, "80"))
+	}
+	return append(candidates, "")
+}
+
+func filterVirtualHost(invocations []*Invocation, host string) []*Invocation {
+	filtered := []*Invocation{}
+	for _, invocation := range invocations {
+		for _, candidate := range virtualHostCandidates(invocation.GetHost()) {
+			if candidate == host {
+				filtered = append(filtered, invocation)
+				break
+			}
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,131 @@
+package mockhttp
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Host(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should dispatch the requests on their host", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithoutListener())
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Host("auth.example.com").Stub(http.MethodGet, "/health").WithBody(http.StatusOK, []byte("auth"), "text/plain")
+		mockedAPI.Host("api.example.com").Stub(http.MethodGet, "/health").WithBody(http.StatusOK, []byte("api"), "text/plain")
+		client := mockedAPI.InterceptHosts("auth.example.com", "api.example.com")
+
+		// Act
+		authBody := getBody(t, client, "https://auth.example.com/health")
+		apiBody := getBody(t, client, "https://API.example.com:8443/health")
+
+		// Assert
+		assert := assertions.New(t)
+		assert.Equal("auth", authBody)
+		assert.Equal("api", apiBody)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should fall back on the stubs of any host", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithoutListener())
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/users/{id}").WithBody(http.StatusOK, []byte("any"), "text/plain")
+		mockedAPI.Host("api.example.com").Stub(http.MethodGet, "/users/{id}").WithBody(http.StatusOK, []byte("api"), "text/plain")
+		client := mockedAPI.InterceptHosts("auth.example.com", "api.example.com")
+
+		// Act
+		authBody := getBody(t, client, "https://auth.example.com/users/1")
+		apiBody := getBody(t, client, "https://api.example.com/users/1")
+
+		// Assert
+		assert := assertions.New(t)
+		assert.Equal("any", authBody)
+		assert.Equal("api", apiBody)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should not serve the stubs of another host", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithoutListener())
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Host("api.example.com").Stub(http.MethodGet, "/health").WithStatusCode(http.StatusOK)
+
+		// Act
+		response, err := mockedAPI.InterceptHosts("auth.example.com").Get("https://auth.example.com/health")
+
+		// Assert
+		require.NoError(t, err)
+		_, _ = io.ReadAll(response.Body) // the body ends once the invocation is handled
+		assertions.Equal(t, http.StatusNotFound, response.StatusCode)
+		testState.AssertFailedWithFatal()
+	})
+
+	t.Run("should match the hosts registered with the default port", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithoutListener())
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Host("api.example.com:443").Stub(http.MethodGet, "/health").WithBody(http.StatusOK, []byte("https"), "text/plain")
+		mockedAPI.Host("api.example.com:80").Stub(http.MethodGet, "/version").WithBody(http.StatusOK, []byte("http"), "text/plain")
+		client := mockedAPI.InterceptHosts("api.example.com")
+
+		// Act
+		httpsBody := getBody(t, client, "https://api.example.com/health")
+		httpBody := getBody(t, client, "http://api.example.com/version")
+
+		// Assert
+		assert := assertions.New(t)
+		assert.Equal("https", httpsBody)
+		assert.Equal("http", httpBody)
+		mockedAPI.Host("api.example.com:443").Verify(http.MethodGet, "/health").HasBeenCalledOnce()
+		mockedAPI.Host("api.example.com:8443").Verify(http.MethodGet, "/health").HasNotBeenCalled()
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("Verify() should only verify the invocations of the host", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState, WithoutListener())
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.Stub(http.MethodGet, "/health").WithStatusCode(http.StatusOK)
+		client := mockedAPI.InterceptHosts("auth.example.com", "api.example.com")
+
+		// Act
+		getBody(t, client, "https://auth.example.com/health")
+		getBody(t, client, "https://api.example.com/health")
+		getBody(t, client, "https://api.example.com:8443/health")
+
+		// Assert
+		mockedAPI.Verify(http.MethodGet, "/health").HasBeenCalled(3)
+		mockedAPI.Host("auth.example.com").Verify(http.MethodGet, "/health").HasBeenCalledOnce().WithHost("auth.example.com")
+		mockedAPI.Host("api.example.com").Verify(http.MethodGet, "/health").HasBeenCalled(2)
+		mockedAPI.Host("api.example.com:8443").Verify(http.MethodGet, "/health").HasBeenCalledOnce()
+		mockedAPI.Host("other.example.com").Verify(http.MethodGet, "/health").HasNotBeenCalled()
+		testState.AssertDidNotFailed()
+	})
+}
+
+func getBody(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+	response, err := client.Get(url)
+	require.NoError(t, err)
+	defer func() { _ = response.Body.Close() }()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	return string(body)
+}
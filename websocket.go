@@ -0,0 +1,359 @@
+package mockhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocketFrameType is the type of a WebSocket frame.
+type WebSocketFrameType string
+
+const (
+	WebSocketText   WebSocketFrameType = "text"
+	WebSocketBinary WebSocketFrameType = "binary"
+	WebSocketPing   WebSocketFrameType = "ping"
+	WebSocketPong   WebSocketFrameType = "pong"
+	WebSocketClose  WebSocketFrameType = "close"
+)
+
+// WebSocketFrame is a frame exchanged on a WebSocket connection.
+type WebSocketFrame struct {
+	// FromClient is true for the frames sent by the client, false for the ones sent by the mocked API.
+	FromClient bool
+	Type       WebSocketFrameType
+	Data       []byte
+	// CloseCode is the status code of a close frame.
+	CloseCode int
+	// ReceivedAt is the time the frame was sent or received by the mocked API.
+	ReceivedAt time.Time
+}
+
+// WebSocketMessageMatcher reports whether a message sent by the client is the expected one.
+type WebSocketMessageMatcher func(messageType WebSocketFrameType, data []byte) bool
+
+// WebSocketStub is a WebSocket endpoint of a mocked API, playing a script for each connection, see
+// APIMock.StubWebSocket. Every frame exchanged is recorded on the connections.
+type WebSocketStub struct {
+	api         *APIMock
+	steps       []webSocketStep
+	connections []*WebSocketConnection
+	upgrader    websocket.Upgrader
+	mu          sync.Mutex
+}
+
+// webSocketStep is a step of a WebSocket script, returning false when the script must stop.
+type webSocketStep func(connection *WebSocketConnection) bool
+
+// StubWebSocket creates a WebSocket endpoint for the given path, which may contain parameters as for Stub. Its script
+// is played for each connection, the connection being kept open once the script ends until the client closes it.
+// The upgrade requests are recorded as GET invocations, but cannot go through the in-memory transport (see Transport):
+//
+//	mockedAPI.StubWebSocket("/chat").
+//		ExpectJSON(map[string]any{"type": "join"}).
+//		SendJSON(map[string]any{"type": "welcome"}).
+//		Wait(100 * time.Millisecond).
+//		SendText("bye").
+//		Close(websocket.CloseNormalClosure, "done")
+func (mockedAPI *APIMock) StubWebSocket(path string) *WebSocketStub {
+	stub := &WebSocketStub{
+		api: mockedAPI,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(request *http.Request) bool { return true },
+		},
+	}
+	mockedAPI.Stub(http.MethodGet, path).With(stub.handler)
+	mockedAPI.testState.Cleanup(stub.closeConnections)
+	return stub
+}
+
+// ExpectMessage waits for the next message of the client and fails the test when the matcher does not accept it,
+// the connection then being closed with the policy violation status code.
+func (stub *WebSocketStub) ExpectMessage(description string, matcher WebSocketMessageMatcher) *WebSocketStub {
+	return stub.addStep(func(connection *WebSocketConnection) bool {
+		messageType, data, err := connection.read()
+		if err != nil {
+			stub.api.testState.Errorf("WebSocket connection closed while expecting %s: %v", description, err)
+			return false
+		}
+		if !matcher(messageType, data) {
+			stub.api.testState.Errorf("unexpected WebSocket message %q where %s was expected", data, description)
+			connection.close(websocket.ClosePolicyViolation, "unexpected message")
+			return false
+		}
+		return true
+	})
+}
+
+// ExpectText waits for the next message of the client and fails the test when it is not the expected text.
+func (stub *WebSocketStub) ExpectText(expected string) *WebSocketStub {
+	return stub.ExpectMessage(fmt.Sprintf("text %q", expected), func(messageType WebSocketFrameType, data []byte) bool {
+		return messageType == WebSocketText && string(data) == expected
+	})
+}
+
+// ExpectJSON waits for the next message of the client and fails the test when it is not the JSON representation of
+// the expected value.
+func (stub *WebSocketStub) ExpectJSON(expected any) *WebSocketStub {
+	normalized, err := normalizeJSON(expected)
+	if err != nil {
+		stub.api.testState.Fatal(err)
+	}
+	return stub.ExpectMessage(fmt.Sprintf("JSON %v", expected), func(messageType WebSocketFrameType, data []byte) bool {
+		var actual any
+		return json.Unmarshal(data, &actual) == nil && reflect.DeepEqual(normalized, actual)
+	})
+}
+
+// SendText sends a text message to the client.
+func (stub *WebSocketStub) SendText(text string) *WebSocketStub {
+	return stub.addStep(func(connection *WebSocketConnection) bool {
+		return connection.write(WebSocketText, []byte(text))
+	})
+}
+
+// SendBinary sends a binary message to the client.
+func (stub *WebSocketStub) SendBinary(data []byte) *WebSocketStub {
+	return stub.addStep(func(connection *WebSocketConnection) bool {
+		return connection.write(WebSocketBinary, data)
+	})
+}
+
+// SendJSON sends the JSON representation of the value as a text message to the client.
+func (stub *WebSocketStub) SendJSON(value any) *WebSocketStub {
+	data, err := json.Marshal(value)
+	if err != nil {
+		stub.api.testState.Fatal(err)
+	}
+	return stub.addStep(func(connection *WebSocketConnection) bool {
+		return connection.write(WebSocketText, data)
+	})
+}
+
+// Ping sends a ping to the client, whose pong is recorded as the next messages are read.
+func (stub *WebSocketStub) Ping(data []byte) *WebSocketStub {
+	return stub.addStep(func(connection *WebSocketConnection) bool {
+		return connection.write(WebSocketPing, data)
+	})
+}
+
+// Wait pauses the script for the given delay.
+func (stub *WebSocketStub) Wait(delay time.Duration) *WebSocketStub {
+	return stub.addStep(func(connection *WebSocketConnection) bool {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			return true
+		case <-connection.stopped:
+			return false
+		}
+	})
+}
+
+// Close closes the connection with the given status code (e.g. websocket.CloseNormalClosure) and reason, ending the
+// script.
+func (stub *WebSocketStub) Close(code int, reason string) *WebSocketStub {
+	return stub.addStep(func(connection *WebSocketConnection) bool {
+		connection.close(code, reason)
+		return false
+	})
+}
+
+func (stub *WebSocketStub) addStep(step webSocketStep) *WebSocketStub {
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	stub.steps = append(stub.steps, step)
+	return stub
+}
+
+// Connections returns the connections made to the endpoint so far.
+func (stub *WebSocketStub) Connections() []*WebSocketConnection {
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	return append([]*WebSocketConnection{}, stub.connections...)
+}
+
+// HasBeenConnected asserts that the endpoint has been connected to the expected number of times then returns the
+// connections.
+func (stub *WebSocketStub) HasBeenConnected(expectedConnectionsCount int) []*WebSocketConnection {
+	connections := stub.Connections()
+	if len(connections) != expectedConnectionsCount {
+		stub.api.testState.Fatalf("got %d WebSocket connections but was expecting %d\n", len(connections), expectedConnectionsCount)
+	}
+	return connections
+}
+
+func (stub *WebSocketStub) handler(writer http.ResponseWriter, request *http.Request) {
+	conn, err := stub.upgrader.Upgrade(writer, request, nil)
+	if err != nil {
+		// the upgrader has already replied with an error status
+		return
+	}
+	connection := &WebSocketConnection{request: request, conn: conn, done: make(chan struct{}), stopped: make(chan struct{})}
+	conn.SetPingHandler(func(data string) error {
+		connection.record(WebSocketFrame{FromClient: true, Type: WebSocketPing, Data: []byte(data)})
+		connection.record(WebSocketFrame{Type: WebSocketPong, Data: []byte(data)})
+		return connection.writeControl(WebSocketPong, []byte(data))
+	})
+	conn.SetPongHandler(func(data string) error {
+		connection.record(WebSocketFrame{FromClient: true, Type: WebSocketPong, Data: []byte(data)})
+		return nil
+	})
+	conn.SetCloseHandler(func(code int, text string) error {
+		connection.record(WebSocketFrame{FromClient: true, Type: WebSocketClose, Data: []byte(text), CloseCode: code})
+		connection.close(code, "")
+		return nil
+	})
+
+	stub.mu.Lock()
+	stub.connections = append(stub.connections, connection)
+	steps := append([]webSocketStep{}, stub.steps...)
+	stub.mu.Unlock()
+
+	go func() {
+		defer close(connection.done)
+		for _, step := range steps {
+			if !step(connection) {
+				break
+			}
+		}
+		// keep recording the frames of the client until the connection is closed
+		for {
+			if _, _, err := connection.read(); err != nil {
+				break
+			}
+		}
+		_ = conn.Close()
+	}()
+}
+
+func (stub *WebSocketStub) closeConnections() {
+	for _, connection := range stub.Connections() {
+		connection.stop()
+		_ = connection.conn.Close()
+		<-connection.done
+	}
+}
+
+// WebSocketConnection is a connection made to a WebSocket endpoint, along with the frames exchanged.
+type WebSocketConnection struct {
+	request *http.Request
+	conn    *websocket.Conn
+	frames  []WebSocketFrame
+	closed  bool
+	done    chan struct{}
+	// stopped is closed once the connection is closing, ending the pending Wait step of the script.
+	stopped  chan struct{}
+	stopOnce sync.Once
+	writeMu  sync.Mutex
+	mu       sync.Mutex
+}
+
+// GetRequest returns the request opening the connection.
+func (connection *WebSocketConnection) GetRequest() *http.Request {
+	return connection.request
+}
+
+// Frames returns the frames exchanged so far, in order.
+func (connection *WebSocketConnection) Frames() []WebSocketFrame {
+	connection.mu.Lock()
+	defer connection.mu.Unlock()
+	return append([]WebSocketFrame{}, connection.frames...)
+}
+
+// ReceivedMessages returns the data of the text and binary messages sent by the client so far.
+func (connection *WebSocketConnection) ReceivedMessages() []string {
+	messages := []string{}
+	for _, frame := range connection.Frames() {
+		if frame.FromClient && (frame.Type == WebSocketText || frame.Type == WebSocketBinary) {
+			messages = append(messages, string(frame.Data))
+		}
+	}
+	return messages
+}
+
+// WaitClosed waits for the connection to be closed, returning false if it is still open after the timeout.
+func (connection *WebSocketConnection) WaitClosed(timeout time.Duration) bool {
+	select {
+	case <-connection.done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (connection *WebSocketConnection) record(frame WebSocketFrame) {
+	frame.ReceivedAt = time.Now()
+	connection.mu.Lock()
+	defer connection.mu.Unlock()
+	connection.frames = append(connection.frames, frame)
+}
+
+// read reads the next message of the client, recording it along with the control frames read meanwhile.
+func (connection *WebSocketConnection) read() (WebSocketFrameType, []byte, error) {
+	messageType, data, err := connection.conn.ReadMessage()
+	if err != nil {
+		return "", nil, err
+	}
+	frameType := WebSocketText
+	if messageType == websocket.BinaryMessage {
+		frameType = WebSocketBinary
+	}
+	connection.record(WebSocketFrame{FromClient: true, Type: frameType, Data: data})
+	return frameType, data, nil
+}
+
+// write sends a message or a ping to the client, returning false when the connection is broken.
+func (connection *WebSocketConnection) write(frameType WebSocketFrameType, data []byte) bool {
+	connection.record(WebSocketFrame{Type: frameType, Data: data})
+	if frameType == WebSocketPing {
+		return connection.writeControl(frameType, data) == nil
+	}
+	messageType := websocket.TextMessage
+	if frameType == WebSocketBinary {
+		messageType = websocket.BinaryMessage
+	}
+	connection.writeMu.Lock()
+	defer connection.writeMu.Unlock()
+	return connection.conn.WriteMessage(messageType, data) == nil
+}
+
+func (connection *WebSocketConnection) writeControl(frameType WebSocketFrameType, data []byte) error {
+	messageType := map[WebSocketFrameType]int{
+		WebSocketPing:  websocket.PingMessage,
+		WebSocketPong:  websocket.PongMessage,
+		WebSocketClose: websocket.CloseMessage,
+	}[frameType]
+	connection.writeMu.Lock()
+	defer connection.writeMu.Unlock()
+	return connection.conn.WriteControl(messageType, data, time.Now().Add(time.Second))
+}
+
+func (connection *WebSocketConnection) stop() {
+	connection.stopOnce.Do(func() {
+		close(connection.stopped)
+	})
+}
+
+// close sends a close frame to the client, once per connection, the client being expected to close the connection.
+func (connection *WebSocketConnection) close(code int, reason string) {
+	connection.mu.Lock()
+	alreadyClosed := connection.closed
+	connection.closed = true
+	connection.mu.Unlock()
+	connection.stop()
+	if alreadyClosed {
+		return
+	}
+	if code != websocket.CloseNoStatusReceived {
+		connection.record(WebSocketFrame{Type: WebSocketClose, Data: []byte(reason), CloseCode: code})
+		_ = connection.writeControl(WebSocketClose, websocket.FormatCloseMessage(code, reason))
+	}
+	_ = connection.conn.SetReadDeadline(time.Now().Add(time.Second))
+}
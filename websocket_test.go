@@ -0,0 +1,183 @@
+package mockhttp
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StubWebSocket(t *testing.T) {
+	t.Parallel()
+
+	dial := func(t *testing.T, mockedAPI *APIMock, path string) *websocket.Conn {
+		t.Helper()
+		url := "ws" + strings.TrimPrefix(mockedAPI.GetURL().String(), "http") + path
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	}
+
+	t.Run("should play the script", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		stub := mockedAPI.StubWebSocket("/chat/{room}").
+			ExpectJSON(map[string]any{"type": "join"}).
+			SendJSON(map[string]any{"type": "welcome"}).
+			Wait(10*time.Millisecond).
+			SendBinary([]byte{1, 2}).
+			Close(websocket.CloseNormalClosure, "done")
+		conn := dial(t, mockedAPI, "/chat/general")
+
+		// Act
+		require.NoError(t, conn.WriteJSON(map[string]any{"type": "join"}))
+		_, welcome, err := conn.ReadMessage()
+		require.NoError(t, err)
+		messageType, binary, err := conn.ReadMessage()
+		require.NoError(t, err)
+		_, _, closeErr := conn.ReadMessage()
+
+		// Assert
+		assert := assertions.New(t)
+		assert.JSONEq(`{"type": "welcome"}`, string(welcome))
+		assert.Equal(websocket.BinaryMessage, messageType)
+		assert.Equal([]byte{1, 2}, binary)
+		assert.True(websocket.IsCloseError(closeErr, websocket.CloseNormalClosure))
+		connection := stub.HasBeenConnected(1)[0]
+		require.True(t, connection.WaitClosed(time.Second))
+		assert.Equal([]string{"{\"type\":\"join\"}\n"}, connection.ReceivedMessages())
+		frames := connection.Frames()
+		require.Len(t, frames, 5)
+		assert.Equal(WebSocketText, frames[0].Type)
+		assert.True(frames[0].FromClient)
+		assert.Equal(WebSocketText, frames[1].Type)
+		assert.False(frames[1].FromClient)
+		assert.Equal(WebSocketBinary, frames[2].Type)
+		assert.Equal(WebSocketClose, frames[3].Type)
+		assert.Equal(websocket.CloseNormalClosure, frames[3].CloseCode)
+		assert.Equal("done", string(frames[3].Data))
+		assert.Equal(WebSocketClose, frames[4].Type)
+		assert.True(frames[4].FromClient)
+		mockedAPI.Verify(http.MethodGet, "/chat/general").HasBeenCalledOnce()
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should fail on unexpected message", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		stub := mockedAPI.StubWebSocket("/chat").ExpectText("hello")
+		conn := dial(t, mockedAPI, "/chat")
+
+		// Act
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("goodbye")))
+		_, _, err := conn.ReadMessage()
+
+		// Assert
+		assertions.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation))
+		require.True(t, stub.HasBeenConnected(1)[0].WaitClosed(time.Second))
+		testState.AssertFailedWithError()
+	})
+
+	t.Run("should record the ping and pong frames", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		stub := mockedAPI.StubWebSocket("/chat").Ping([]byte("server")).ExpectText("after ping")
+		conn := dial(t, mockedAPI, "/chat")
+		pinged := make(chan string, 1)
+		conn.SetPingHandler(func(data string) error {
+			pinged <- data
+			return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		})
+		go func() {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		// Act
+		assertions.Equal(t, "server", <-pinged)
+		require.NoError(t, conn.WriteControl(websocket.PingMessage, []byte("client"), time.Now().Add(time.Second)))
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("after ping")))
+		require.NoError(t, conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "")))
+
+		// Assert
+		connection := stub.HasBeenConnected(1)[0]
+		require.True(t, connection.WaitClosed(time.Second))
+		types := []WebSocketFrameType{}
+		for _, frame := range connection.Frames() {
+			types = append(types, frame.Type)
+		}
+		assertions.Equal(t, []WebSocketFrameType{
+			WebSocketPing, WebSocketPong, WebSocketPing, WebSocketPong, WebSocketText, WebSocketClose, WebSocketClose,
+		}, types)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("should serve REST stubs along with WebSocket endpoints", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.StubWebSocket("/chat").SendText("hello")
+		mockedAPI.Stub(http.MethodGet, "/rooms").WithJSON(http.StatusOK, []string{"general"})
+
+		// Act
+		conn := dial(t, mockedAPI, "/chat")
+		_, hello, err := conn.ReadMessage()
+		require.NoError(t, err)
+		response, err := http.Get(mockedAPI.GetURL().String() + "/rooms")
+		require.NoError(t, err)
+
+		// Assert
+		assert := assertions.New(t)
+		assert.Equal("hello", string(hello))
+		assert.Equal(http.StatusOK, response.StatusCode)
+		mockedAPI.Verify(http.MethodGet, "/chat").HasBeenCalledOnce()
+		mockedAPI.Verify(http.MethodGet, "/rooms").HasBeenCalledOnce()
+		testState.AssertDidNotFailed()
+	})
+	t.Run("should interrupt the waiting script when the connections are closed", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		stub := mockedAPI.StubWebSocket("/chat").SendText("hello").Wait(time.Hour).SendText("goodbye")
+		conn := dial(t, mockedAPI, "/chat")
+		_, _, err := conn.ReadMessage()
+		require.NoError(t, err)
+		start := time.Now()
+
+		// Act
+		for _, cleanup := range testState.GetCleanups() {
+			cleanup()
+		}
+
+		// Assert
+		assertions.Less(t, time.Since(start), time.Second)
+		connection := stub.HasBeenConnected(1)[0]
+		require.True(t, connection.WaitClosed(time.Second))
+		frames := connection.Frames()
+		require.Len(t, frames, 1)
+		assertions.Equal(t, "hello", string(frames[0].Data))
+		testState.AssertDidNotFailed()
+	})
+}
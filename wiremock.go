@@ -0,0 +1,457 @@
+package mockhttp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ImportWireMockMappings registers the stubs described by WireMock JSON stub mappings. The reader contains either a
+// single mapping or a document listing them under "mappings". Requests are matched on their method and URL (url,
+// urlPath, urlPattern, urlPathPattern or urlPathTemplate), query parameters, headers and body patterns (equalTo,
+// contains, matches, doesNotMatch, equalToJson and absent). Responses support status, headers, body, jsonBody,
+// base64Body and fixedDelayMilliseconds. Scenarios are supported as well, see StubBuilder.InScenario. As with WireMock,
+// the most recently imported mapping matching a request handles it, except that the mappings of an exact path (url
+// and urlPath) take precedence over the pattern and template ones.
+func (mockedAPI *APIMock) ImportWireMockMappings(reader io.Reader) *APIMock {
+	mappings, err := readWireMockMappings(reader)
+	if err == nil {
+		for _, mapping := range mappings {
+			if err = mapping.stub(mockedAPI); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		mockedAPI.testState.Fatal(err)
+	}
+	return mockedAPI
+}
+
+// ExportWireMockMappings writes the registered stubs as a WireMock JSON stub mappings document.
+// Stubs using a custom handler (see StubBuilder.With), custom request matchers or a virtual host (see APIMock.Host)
+// cannot be exported and are skipped.
+func (mockedAPI *APIMock) ExportWireMockMappings(writer io.Writer) error {
+	mockedAPI.mu.Lock()
+	calls := make([]HTTPCall, 0, len(mockedAPI.calls))
+	for call := range mockedAPI.calls {
+		calls = append(calls, call)
+	}
+	sort.Slice(calls, func(i, j int) bool {
+		if calls[i].Path != calls[j].Path {
+			return calls[i].Path < calls[j].Path
+		}
+		return calls[i].Method < calls[j].Method
+	})
+
+	document := wireMockMappings{Mappings: []*wireMockMapping{}}
+	for _, call := range calls {
+		for _, registered := range mockedAPI.calls[call] {
+			if registered.response == nil || call.Host != "" || (registered.wireMock == nil && len(registered.matchers) > 0) {
+				continue
+			}
+			document.Mappings = append(document.Mappings, newWireMockMapping(mockedAPI, call, registered))
+		}
+	}
+	mockedAPI.mu.Unlock()
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+type wireMockMappings struct {
+	Mappings []*wireMockMapping `json:"mappings"`
+}
+
+type wireMockMapping struct {
+	Name                  string           `json:"name,omitempty"`
+	Request               wireMockRequest  `json:"request"`
+	Response              wireMockResponse `json:"response"`
+	ScenarioName          string           `json:"scenarioName,omitempty"`
+	RequiredScenarioState string           `json:"requiredScenarioState,omitempty"`
+	NewScenarioState      string           `json:"newScenarioState,omitempty"`
+}
+
+type wireMockRequest struct {
+	Method          string                      `json:"method,omitempty"`
+	URL             string                      `json:"url,omitempty"`
+	URLPath         string                      `json:"urlPath,omitempty"`
+	URLPattern      string                      `json:"urlPattern,omitempty"`
+	URLPathPattern  string                      `json:"urlPathPattern,omitempty"`
+	URLPathTemplate string                      `json:"urlPathTemplate,omitempty"`
+	QueryParameters map[string]*wireMockPattern `json:"queryParameters,omitempty"`
+	Headers         map[string]*wireMockPattern `json:"headers,omitempty"`
+	BodyPatterns    []*wireMockPattern          `json:"bodyPatterns,omitempty"`
+}
+
+type wireMockPattern struct {
+	EqualTo             *string `json:"equalTo,omitempty"`
+	Contains            *string `json:"contains,omitempty"`
+	Matches             *string `json:"matches,omitempty"`
+	DoesNotMatch        *string `json:"doesNotMatch,omitempty"`
+	EqualToJSON         any     `json:"equalToJson,omitempty"`
+	MatchesJSONPath     any     `json:"matchesJsonPath,omitempty"`
+	Absent              bool    `json:"absent,omitempty"`
+	CaseInsensitive     bool    `json:"caseInsensitive,omitempty"`
+	IgnoreExtraElements bool    `json:"ignoreExtraElements,omitempty"`
+
+	matches      *regexp.Regexp
+	doesNotMatch *regexp.Regexp
+	expectedJSON any
+}
+
+type wireMockResponse struct {
+	Status                 int             `json:"status,omitempty"`
+	Headers                wireMockHeaders `json:"headers,omitempty"`
+	Body                   string          `json:"body,omitempty"`
+	JSONBody               json.RawMessage `json:"jsonBody,omitempty"`
+	Base64Body             string          `json:"base64Body,omitempty"`
+	BodyFileName           string          `json:"bodyFileName,omitempty"`
+	FixedDelayMilliseconds int             `json:"fixedDelayMilliseconds,omitempty"`
+}
+
+// wireMockHeaders are response headers, each one having either a single value or a list of values.
+type wireMockHeaders map[string][]string
+
+// UnmarshalJSON decodes headers having either a single value or a list of values.
+func (headers *wireMockHeaders) UnmarshalJSON(data []byte) error {
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*headers = wireMockHeaders{}
+	for name, value := range raw {
+		var values []string
+		if err := json.Unmarshal(value, &values); err != nil {
+			var single string
+			if err := json.Unmarshal(value, &single); err != nil {
+				return fmt.Errorf("invalid value of header '%s'", name)
+			}
+			values = []string{single}
+		}
+		(*headers)[name] = values
+	}
+	return nil
+}
+
+// MarshalJSON encodes single valued headers as a string and the other ones as a list.
+func (headers wireMockHeaders) MarshalJSON() ([]byte, error) {
+	raw := map[string]any{}
+	for name, values := range headers {
+		if len(values) == 1 {
+			raw[name] = values[0]
+		} else {
+			raw[name] = values
+		}
+	}
+	return json.Marshal(raw)
+}
+
+func readWireMockMappings(reader io.Reader) ([]*wireMockMapping, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("invalid WireMock mappings: %w", err)
+	}
+	if _, ok := fields["mappings"]; ok {
+		document := wireMockMappings{}
+		if err := json.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("invalid WireMock mappings: %w", err)
+		}
+		return document.Mappings, nil
+	}
+	mapping := &wireMockMapping{}
+	if err := json.Unmarshal(data, mapping); err != nil {
+		return nil, fmt.Errorf("invalid WireMock mapping: %w", err)
+	}
+	return []*wireMockMapping{mapping}, nil
+}
+
+// stub registers the stub described by the mapping on the mocked API.
+func (mapping *wireMockMapping) stub(mockedAPI *APIMock) error {
+	request := mapping.Request
+	method := strings.ToLower(request.Method)
+	if method == "" {
+		method = anyMethod
+	}
+	builder := &StubBuilder{
+		api:      mockedAPI,
+		call:     &HTTPCall{Method: method},
+		wireMock: &request,
+	}
+
+	switch {
+	case request.URLPath != "":
+		builder.call.Path = request.URLPath
+	case request.URLPathTemplate != "":
+		builder.call.Path = request.URLPathTemplate
+	case request.URLPathPattern != "":
+		pattern, err := regexp.Compile("^(?:" + request.URLPathPattern + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid WireMock urlPathPattern: %w", err)
+		}
+		builder.call.Path = request.URLPathPattern
+		builder.pattern = pattern
+	case request.URL != "":
+		path, _, _ := strings.Cut(request.URL, "?")
+		builder.call.Path = path
+		builder.Matching(func(actual *http.Request, payload []byte) bool {
+			return actual.URL.RequestURI() == request.URL
+		})
+	default:
+		urlPattern := request.URLPattern
+		if urlPattern == "" {
+			urlPattern = ".*"
+		}
+		pattern, err := regexp.Compile("^(?:" + urlPattern + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid WireMock urlPattern: %w", err)
+		}
+		builder.call.Path = urlPattern
+		builder.pattern = regexp.MustCompile(".*")
+		builder.Matching(func(actual *http.Request, payload []byte) bool {
+			return pattern.MatchString(actual.URL.RequestURI())
+		})
+	}
+
+	for name, pattern := range request.QueryParameters {
+		if err := pattern.compile(); err != nil {
+			return fmt.Errorf("invalid WireMock query parameter '%s' pattern: %w", name, err)
+		}
+		builder.Matching(func(actual *http.Request, payload []byte) bool {
+			values, present := actual.URL.Query()[name]
+			return pattern.matchValues(values, present)
+		})
+	}
+	for name, pattern := range request.Headers {
+		if err := pattern.compile(); err != nil {
+			return fmt.Errorf("invalid WireMock header '%s' pattern: %w", name, err)
+		}
+		builder.Matching(func(actual *http.Request, payload []byte) bool {
+			values := actual.Header.Values(name)
+			return pattern.matchValues(values, len(values) > 0)
+		})
+	}
+	for _, pattern := range request.BodyPatterns {
+		if err := pattern.compile(); err != nil {
+			return fmt.Errorf("invalid WireMock body pattern: %w", err)
+		}
+		builder.Matching(func(actual *http.Request, payload []byte) bool {
+			return pattern.match(string(payload), len(payload) > 0)
+		})
+	}
+
+	if mapping.ScenarioName != "" {
+		builder.InScenario(mapping.ScenarioName, mapping.RequiredScenarioState, mapping.NewScenarioState)
+	}
+	builder.WithDelay(time.Duration(mapping.Response.FixedDelayMilliseconds) * time.Millisecond)
+
+	response, err := mapping.Response.stubResponse()
+	if err != nil {
+		return err
+	}
+	builder.withResponse(response)
+	return nil
+}
+
+func (response *wireMockResponse) stubResponse() (*stubResponse, error) {
+	stubbed := &stubResponse{
+		statusCode: response.Status,
+		header:     http.Header{},
+	}
+	if stubbed.statusCode == 0 {
+		stubbed.statusCode = http.StatusOK
+	}
+	for name, values := range response.Headers {
+		for _, value := range values {
+			stubbed.header.Add(name, value)
+		}
+	}
+
+	switch {
+	case response.BodyFileName != "":
+		return nil, errors.New("WireMock bodyFileName responses are not supported, use body, jsonBody or base64Body")
+	case len(response.JSONBody) > 0:
+		stubbed.body = response.JSONBody
+		if stubbed.header.Get("Content-Type") == "" {
+			stubbed.header.Set("Content-Type", "application/json")
+		}
+	case response.Base64Body != "":
+		body, err := base64.StdEncoding.DecodeString(response.Base64Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WireMock base64Body: %w", err)
+		}
+		stubbed.body = body
+	default:
+		stubbed.body = []byte(response.Body)
+	}
+	return stubbed, nil
+}
+
+// compile validates the pattern and prepares its regular expressions and expected JSON value.
+func (pattern *wireMockPattern) compile() error {
+	if pattern.MatchesJSONPath != nil {
+		return errors.New("matchesJsonPath is not supported")
+	}
+	var err error
+	if pattern.Matches != nil {
+		if pattern.matches, err = regexp.Compile("^(?:" + *pattern.Matches + ")$"); err != nil {
+			return err
+		}
+	}
+	if pattern.DoesNotMatch != nil {
+		if pattern.doesNotMatch, err = regexp.Compile("^(?:" + *pattern.DoesNotMatch + ")$"); err != nil {
+			return err
+		}
+	}
+	pattern.expectedJSON = pattern.EqualToJSON
+	if text, ok := pattern.EqualToJSON.(string); ok {
+		if err = json.Unmarshal([]byte(text), &pattern.expectedJSON); err != nil {
+			return fmt.Errorf("invalid equalToJson value: %w", err)
+		}
+	}
+	return nil
+}
+
+// matchValues reports whether one of the values matches the pattern.
+func (pattern *wireMockPattern) matchValues(values []string, present bool) bool {
+	if !present {
+		return pattern.match("", false)
+	}
+	for _, value := range values {
+		if pattern.match(value, true) {
+			return true
+		}
+	}
+	return false
+}
+
+func (pattern *wireMockPattern) match(value string, present bool) bool {
+	if pattern.Absent {
+		return !present
+	}
+	if !present && pattern.EqualTo == nil {
+		return false
+	}
+	equal := func(expected string, actual string) bool {
+		if pattern.CaseInsensitive {
+			return strings.EqualFold(expected, actual)
+		}
+		return expected == actual
+	}
+	switch {
+	case pattern.EqualTo != nil && !equal(*pattern.EqualTo, value):
+		return false
+	case pattern.Contains != nil && !strings.Contains(value, *pattern.Contains):
+		return false
+	case pattern.matches != nil && !pattern.matches.MatchString(value):
+		return false
+	case pattern.doesNotMatch != nil && pattern.doesNotMatch.MatchString(value):
+		return false
+	case pattern.expectedJSON != nil:
+		var actual any
+		if err := json.Unmarshal([]byte(value), &actual); err != nil {
+			return false
+		}
+		return matchesJSON(pattern.expectedJSON, actual, pattern.IgnoreExtraElements)
+	}
+	return true
+}
+
+// matchesJSON compares decoded JSON values, ignoring the object fields not expected when ignoreExtraElements is set.
+func matchesJSON(expected any, actual any, ignoreExtraElements bool) bool {
+	if !ignoreExtraElements {
+		return reflect.DeepEqual(expected, actual)
+	}
+	switch typedExpected := expected.(type) {
+	case map[string]any:
+		typedActual, ok := actual.(map[string]any)
+		if !ok {
+			return false
+		}
+		for key, value := range typedExpected {
+			if !matchesJSON(value, typedActual[key], true) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		typedActual, ok := actual.([]any)
+		if !ok || len(typedActual) != len(typedExpected) {
+			return false
+		}
+		for i, value := range typedExpected {
+			if !matchesJSON(value, typedActual[i], true) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+// newWireMockMapping returns the mapping describing the stub. It must be called while holding the mocked API lock.
+func newWireMockMapping(mockedAPI *APIMock, call HTTPCall, registered *registeredStub) *wireMockMapping {
+	mapping := &wireMockMapping{
+		Response: wireMockResponse{
+			Status:                 registered.response.statusCode,
+			Headers:                wireMockHeaders(registered.response.header.Clone()),
+			FixedDelayMilliseconds: int(registered.delay / time.Millisecond),
+		},
+	}
+	if len(mapping.Response.Headers) == 0 {
+		mapping.Response.Headers = nil
+	}
+
+	body := registered.response.body
+	switch {
+	case len(body) == 0:
+	case isJSONContentType(registered.response.header.Get("Content-Type")) && json.Valid(body):
+		mapping.Response.JSONBody = body
+	case utf8.Valid(body):
+		mapping.Response.Body = string(body)
+	default:
+		mapping.Response.Base64Body = base64.StdEncoding.EncodeToString(body)
+	}
+
+	if registered.scenario != nil {
+		mapping.ScenarioName = registered.scenario.name
+		mapping.RequiredScenarioState = registered.scenario.requiredState
+		mapping.NewScenarioState = registered.scenario.newState
+	}
+
+	if registered.wireMock != nil {
+		mapping.Request = *registered.wireMock
+		return mapping
+	}
+	mapping.Request.Method = strings.ToUpper(call.Method)
+	if call.Method == anyMethod {
+		mapping.Request.Method = "ANY"
+	}
+	mapping.Request.URLPath = call.Path
+	for _, template := range mockedAPI.templates {
+		if template.call != call {
+			continue
+		}
+		mapping.Request.URLPath = ""
+		if isPathTemplate(call.Path) {
+			mapping.Request.URLPathTemplate = call.Path
+		} else {
+			mapping.Request.URLPathPattern = call.Path
+		}
+	}
+	return mapping
+}
@@ -0,0 +1,225 @@
+package mockhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/le-yams/gotestingmock"
+	assertions "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const wireMockMappingsDocument = `{
+  "mappings": [
+    {
+      "request": {
+        "method": "GET",
+        "urlPathTemplate": "/users/{id}",
+        "headers": {"Accept": {"contains": "json"}}
+      },
+      "response": {
+        "status": 200,
+        "headers": {"Content-Type": "application/json"},
+        "jsonBody": {"name": "John"}
+      }
+    },
+    {
+      "request": {"method": "POST", "urlPath": "/users"},
+      "response": {"status": 400}
+    },
+    {
+      "request": {
+        "method": "POST",
+        "urlPath": "/users",
+        "bodyPatterns": [{"equalToJson": {"name": "John"}, "ignoreExtraElements": true}]
+      },
+      "response": {"status": 201, "body": "created"}
+    },
+    {
+      "request": {"method": "ANY", "urlPathPattern": "/orders/[0-9]+", "queryParameters": {"debug": {"absent": true}}},
+      "response": {"status": 200, "body": "order", "fixedDelayMilliseconds": 1}
+    }
+  ]
+}`
+
+func Test_WireMock(t *testing.T) {
+	t.Parallel()
+
+	send := func(t *testing.T, mockedAPI *APIMock, method string, path string, body string, header http.Header) (int, string) {
+		request, err := http.NewRequest(method, mockedAPI.GetURL().String()+path, strings.NewReader(body))
+		require.NoError(t, err)
+		for name, values := range header {
+			request.Header[name] = values
+		}
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		responseBody, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response.StatusCode, string(responseBody)
+	}
+
+	t.Run("ImportWireMockMappings() should stub the mappings", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		mockedAPI.ImportWireMockMappings(strings.NewReader(wireMockMappingsDocument))
+
+		// Assert
+		assert := assertions.New(t)
+		status, body := send(t, mockedAPI, http.MethodGet, "/users/42", "", http.Header{"Accept": {"application/json"}})
+		assert.Equal(http.StatusOK, status)
+		assert.JSONEq(`{"name": "John"}`, body)
+		status, body = send(t, mockedAPI, http.MethodPost, "/users", `{"name": "John", "age": 42}`, nil)
+		assert.Equal(http.StatusCreated, status)
+		assert.Equal("created", body)
+		status, _ = send(t, mockedAPI, http.MethodPost, "/users", `{"name": "Jane"}`, nil)
+		assert.Equal(http.StatusBadRequest, status)
+		status, body = send(t, mockedAPI, http.MethodDelete, "/orders/7", "", nil)
+		assert.Equal(http.StatusOK, status)
+		assert.Equal("order", body)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("ImportWireMockMappings() should not stub a request not matching the mapping", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.ImportWireMockMappings(strings.NewReader(wireMockMappingsDocument))
+
+		// Act
+		status, _ := send(t, mockedAPI, http.MethodGet, "/orders/7?debug=true", "", nil)
+
+		// Assert
+		assertions.New(t).Equal(http.StatusNotFound, status)
+		testState.AssertFailedWithFatal()
+	})
+
+	t.Run("ImportWireMockMappings() should stub the most recently imported overlapping mapping", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.ImportWireMockMappings(strings.NewReader(`{"mappings": [
+			{"request": {"method": "GET", "urlPathPattern": "/orders/.*"}, "response": {"status": 200, "body": "any order"}},
+			{"request": {"method": "GET", "urlPathPattern": "/orders/[0-9]+"}, "response": {"status": 200, "body": "numbered order"}}
+		]}`))
+
+		// Act
+		_, numbered := send(t, mockedAPI, http.MethodGet, "/orders/7", "", nil)
+		_, named := send(t, mockedAPI, http.MethodGet, "/orders/latest", "", nil)
+
+		// Assert
+		assert := assertions.New(t)
+		assert.Equal("numbered order", numbered)
+		assert.Equal("any order", named)
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("ImportWireMockMappings() should support scenarios", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mappings := `{"mappings": [
+			{"scenarioName": "order", "requiredScenarioState": "Started", "newScenarioState": "Shipped",
+			 "request": {"method": "GET", "url": "/order"}, "response": {"status": 200, "body": "pending"}},
+			{"scenarioName": "order", "requiredScenarioState": "Shipped",
+			 "request": {"method": "GET", "url": "/order"}, "response": {"status": 200, "body": "shipped"}}
+		]}`
+
+		// Act
+		mockedAPI.ImportWireMockMappings(strings.NewReader(mappings))
+
+		// Assert
+		assert := assertions.New(t)
+		_, body := send(t, mockedAPI, http.MethodGet, "/order", "", nil)
+		assert.Equal("pending", body)
+		_, body = send(t, mockedAPI, http.MethodGet, "/order", "", nil)
+		assert.Equal("shipped", body)
+		assert.Equal("Shipped", mockedAPI.GetScenarioState("order"))
+		testState.AssertDidNotFailed()
+	})
+
+	t.Run("ImportWireMockMappings() should fail on unsupported mapping", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		mockedAPI.ImportWireMockMappings(strings.NewReader(`{"request": {"urlPath": "/"}, "response": {"bodyFileName": "user.json"}}`))
+
+		// Assert
+		testState.AssertFailedWithFatal()
+	})
+
+	t.Run("ExportWireMockMappings() should write the stubs", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+		mockedAPI.
+			Stub(http.MethodGet, "/users/{id}").WithJSON(http.StatusOK, map[string]any{"name": "John"}).
+			Stub(http.MethodDelete, "/users/{id}").WithStatusCode(http.StatusNoContent).
+			Stub(http.MethodGet, "/health").With(func(writer http.ResponseWriter, request *http.Request) {})
+
+		// Act
+		output := &bytes.Buffer{}
+		err := mockedAPI.ExportWireMockMappings(output)
+
+		// Assert
+		require.NoError(t, err)
+		document := wireMockMappings{}
+		require.NoError(t, json.Unmarshal(output.Bytes(), &document))
+		require.Len(t, document.Mappings, 2)
+
+		assert := assertions.New(t)
+		assert.Equal("DELETE", document.Mappings[0].Request.Method)
+		assert.Equal("/users/{id}", document.Mappings[0].Request.URLPathTemplate)
+		assert.Equal(http.StatusNoContent, document.Mappings[0].Response.Status)
+		assert.Equal("GET", document.Mappings[1].Request.Method)
+		assert.JSONEq(`{"name": "John"}`, string(document.Mappings[1].Response.JSONBody))
+		assert.Equal("application/json", document.Mappings[1].Response.Headers["Content-Type"][0])
+	})
+
+	t.Run("exported mappings should be importable", func(t *testing.T) {
+		t.Parallel()
+		// Arrange
+		testState := testingmock.New(t)
+		exportingAPI := API(testState)
+		t.Cleanup(exportingAPI.Close)
+		exportingAPI.ImportWireMockMappings(strings.NewReader(wireMockMappingsDocument))
+		exported := &bytes.Buffer{}
+		require.NoError(t, exportingAPI.ExportWireMockMappings(exported))
+
+		mockedAPI := API(testState)
+		t.Cleanup(mockedAPI.Close)
+
+		// Act
+		mockedAPI.ImportWireMockMappings(exported)
+
+		// Assert
+		assert := assertions.New(t)
+		status, body := send(t, mockedAPI, http.MethodPost, "/users", `{"name": "John"}`, nil)
+		assert.Equal(http.StatusCreated, status)
+		assert.Equal("created", body)
+		status, _ = send(t, mockedAPI, http.MethodPut, "/orders/1", "", nil)
+		assert.Equal(http.StatusOK, status)
+		testState.AssertDidNotFailed()
+	})
+}